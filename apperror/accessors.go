@@ -0,0 +1,32 @@
+package apperror
+
+import "strconv"
+
+// Field returns the "field" metadata set with WithField, or the empty string if there is none.
+func (err AppError) Field() string {
+	return err.Metadata["field"]
+}
+
+// Row returns the "row" metadata set with WithRow. The boolean is false when the row is
+// absent or is not an integer.
+func (err AppError) Row() (int, bool) {
+	value, ok := err.Metadata["row"]
+	if !ok {
+		return 0, false
+	}
+	row, parseErr := strconv.Atoi(value)
+	if parseErr != nil {
+		return 0, false
+	}
+	return row, true
+}
+
+// Info returns the "info" metadata set with WithInfo, or the empty string if there is none.
+func (err AppError) Info() string {
+	return err.Metadata["info"]
+}
+
+// RequestID returns the "request_id" metadata set with WithRequestID, or the empty string if there is none.
+func (err AppError) RequestID() string {
+	return err.Metadata["request_id"]
+}
@@ -0,0 +1,32 @@
+package apperror
+
+import "sync"
+
+var (
+	categoryAliasesMu sync.RWMutex
+	categoryAliases   = map[string]Category{}
+)
+
+// RegisterCategoryAlias registers a legacy name accepted by ParseCategory for the category,
+// e.g. "NotFoundError" for ErrNotFound. It does not change the output of String().
+// It is safe for concurrent use. Registering an alias again replaces its category.
+func RegisterCategoryAlias(alias string, c Category) {
+	categoryAliasesMu.Lock()
+	defer categoryAliasesMu.Unlock()
+	categoryAliases[alias] = c
+}
+
+// lookupCategoryAlias returns the category registered for the alias.
+func lookupCategoryAlias(alias string) (Category, bool) {
+	categoryAliasesMu.RLock()
+	defer categoryAliasesMu.RUnlock()
+	c, ok := categoryAliases[alias]
+	return c, ok
+}
+
+// unregisterCategoryAlias removes the alias, so tests can undo RegisterCategoryAlias.
+func unregisterCategoryAlias(alias string) {
+	categoryAliasesMu.Lock()
+	defer categoryAliasesMu.Unlock()
+	delete(categoryAliases, alias)
+}
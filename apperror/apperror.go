@@ -1,9 +1,16 @@
 package apperror
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"maps"
+	"net"
 	"net/http"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
 )
 
 type Category int
@@ -16,41 +23,119 @@ const (
 	ErrSecurity
 	ErrForbidden
 	ErrUnauthorized
+	ErrTimeout
+	ErrCanceled
+	ErrTooManyRequests
+	ErrConflict
+	ErrUnavailable
 )
 
+// AllCategories returns every built-in category in declaration order.
+func AllCategories() []Category {
+	return []Category{
+		ErrValidation,
+		ErrInternal,
+		ErrNotFound,
+		ErrMethoNotAllowed,
+		ErrSecurity,
+		ErrForbidden,
+		ErrUnauthorized,
+		ErrTimeout,
+		ErrCanceled,
+		ErrTooManyRequests,
+		ErrConflict,
+		ErrUnavailable,
+	}
+}
+
+// ParseCategory returns the category whose String() is s, or the category registered
+// for s with RegisterCategoryAlias.
+func ParseCategory(s string) (Category, error) {
+	for _, c := range AllCategories() {
+		if c.String() == s {
+			return c, nil
+		}
+	}
+	if c, ok := lookupCategoryAlias(s); ok {
+		return c, nil
+	}
+	return 0, fmt.Errorf("apperror: unknown category %q", s)
+}
+
+// StatusClientClosedRequest is the non-standard status code used when the client canceled the request.
+const StatusClientClosedRequest = 499
+
 // Code represents an error code with a category and an optional internal code.
 // The Category field indicates the type of error, while the Internal field can be used
 // to provide a specific error code for internal use.
+// The Service field namespaces the internal code, so services that reuse the same
+// internal codes can still be told apart in logs and dashboards.
 type Code struct {
 	Category Category
-	Internal int
+	Internal ErrorCode
+	Service  string
+}
+
+// String returns the internal code prefixed by its service, e.g. "billing/1001".
+// If no service is set, only the internal code is returned.
+func (c Code) String() string {
+	if c.Service == "" {
+		return strconv.Itoa(int(c.Internal))
+	}
+	return c.Service + "/" + strconv.Itoa(int(c.Internal))
 }
 
 // AppError represents an application-specific error with additional metadata.
 // It includes an error message, a status code, a category, and an optional internal code.
 // The Metadata map can be used to store additional information about the error.
+// It is allocated lazily by the With* methods, so a nil map must be treated as empty.
+// The Details field can hold an arbitrary structured payload, such as the invalid input.
+// The Severity field overrides the default severity of the category.
+// The StatusText field overrides the reason phrase of the HTTP status line.
+// The Stack field holds the program counters captured by WithStack or Recovered.
+// The Ops field holds the path of operations added by WithOp, outermost first.
+// The Errors field holds the sub-errors of a multi-error built with NewMultiError or Join.
+// The Retryable field reports whether the operation can be retried, e.g. after a dependency outage.
 // The Error interface is implemented to allow easy error handling and logging.
 type AppError struct {
-	Err     error
-	Status  int
-	Code    Code
-	Message string
+	Err        error
+	Status     int
+	StatusText string
+	Code       Code
+	Message    string
+	Severity   Severity
+	Retryable  bool
 
 	Metadata map[string]string
+	Details  any
+	Stack    []uintptr
+	Ops      []string
+	Errors   []*AppError
 }
 
+// DefaultInternalCode maps categories to the internal code used by NewAppError when none
+// is supplied, e.g. a 1000-range for ErrValidation and a 5000-range for ErrInternal.
+// Categories without an entry keep a zero internal code. It must not be modified while
+// errors are being created.
+var DefaultInternalCode = map[Category]int{}
+
 // NewAppError creates a new AppError with the provided error, category, and optional internal code.
-// If internalCode is nil, it will not be included in the error code.
+// If internalCode is nil, the DefaultInternalCode of the category is used, if any.
 // The Status field can be used to set the HTTP status code associated with the error.
 func NewAppError(err error, category Category, internalCode *int) *AppError {
+	if internalCode == nil {
+		if code, ok := DefaultInternalCode[category]; ok {
+			internalCode = &code
+		}
+	}
+
 	if internalCode != nil {
 		return &AppError{
 			Err: err,
 			Code: Code{
 				Category: category,
-				Internal: *internalCode,
+				Internal: ErrorCode(*internalCode),
 			},
-			Metadata: make(map[string]string),
 		}
 	}
 
@@ -59,56 +144,204 @@ func NewAppError(err error, category Category, internalCode *int) *AppError {
 		Code: Code{
 			Category: category,
 		},
-		Metadata: make(map[string]string),
 	}
 }
 
 // Error implements the error interface for AppError.
+// If there is no wrapped error, the Message field is returned.
+// Operations added by WithOp are prefixed as a path, e.g. "handler.Get -> service.GetUser: not found".
 func (err AppError) Error() string {
-	return err.Err.Error()
+	msg := err.Message
+	if err.Err != nil {
+		msg = err.Err.Error()
+	}
+	if len(err.Ops) == 0 {
+		return msg
+	}
+	return strings.Join(err.Ops, " -> ") + ": " + msg
 }
 
-// BadRequest creates a new AppError with a status code of 400 (Bad Request).
+// BadRequest creates a new AppError with the ErrValidation category and a status code of 400 (Bad Request).
 func BadRequest(err error) *AppError {
-	return withStatus(http.StatusBadRequest, err)
+	return withCategory(ErrValidation, err)
 }
 
-// NotFound creates a new AppError with a status code of 404 (Not Found).
+// NotFound creates a new AppError with the ErrNotFound category and a status code of 404 (Not Found).
 func NotFound(err error) *AppError {
-	return withStatus(http.StatusNotFound, err)
+	return withCategory(ErrNotFound, err)
 }
 
-// Unauthorized creates a new AppError with a status code of 401 (Unauthorized).
+// Unauthorized creates a new AppError with the ErrUnauthorized category and a status code of 401 (Unauthorized).
 func Unauthorized(err error) *AppError {
-	return withStatus(http.StatusUnauthorized, err)
+	return withCategory(ErrUnauthorized, err)
 }
 
-// Forbidden creates a new AppError with a status code of 403 (Forbidden).
+// Forbidden creates a new AppError with the ErrForbidden category and a status code of 403 (Forbidden).
 func Forbidden(err error) *AppError {
-	return withStatus(http.StatusForbidden, err)
+	return withCategory(ErrForbidden, err)
 }
 
-// InternalServerError creates a new AppError with a status code of 500 (Internal Server Error).
+// InternalServerError creates a new AppError with the ErrInternal category and a status code of 500 (Internal Server Error).
 func InternalServerError(err error) *AppError {
-	return withStatus(http.StatusInternalServerError, err)
+	return withCategory(ErrInternal, err)
+}
+
+// MethodNotAllowed creates a new AppError with the ErrMethoNotAllowed category and a status code of 405 (Method Not Allowed).
+// The allowed methods are stored with "allow" key in the AppError's metadata, so they can be sent in the Allow header.
+func MethodNotAllowed(err error, allowed ...string) *AppError {
+	return withCategory(ErrMethoNotAllowed, err).withMetadata("allow", strings.Join(allowed, ", "))
+}
+
+// Timeout creates a new AppError with the ErrTimeout category and a status code of 504 (Gateway Timeout).
+func Timeout(err error) *AppError {
+	return withCategory(ErrTimeout, err)
+}
+
+// TooManyRequests creates a new AppError with the ErrTooManyRequests category and a status code of 429 (Too Many Requests).
+func TooManyRequests(err error) *AppError {
+	return withCategory(ErrTooManyRequests, err)
+}
+
+// Conflict creates a new AppError with the ErrConflict category and a status code of 409 (Conflict).
+func Conflict(err error) *AppError {
+	return withCategory(ErrConflict, err)
+}
+
+// Unavailable creates a new AppError with the ErrUnavailable category and a status code of
+// 503 (Service Unavailable), e.g. for a dependency outage. It is Retryable by default.
+func Unavailable(err error) *AppError {
+	e := withCategory(ErrUnavailable, err)
+	e.Retryable = true
+	return e
 }
 
 // WithField adds a field key value to the AppError's metadata.
 func (err AppError) WithField(value string) *AppError {
-	err.Metadata["field"] = value
-	return &err
+	return err.withMetadata("field", value)
 }
 
 // WithRow adds a row key value number to the AppError's metadata.
 func (err AppError) WithRow(row int) *AppError {
-	err.Metadata["row"] = strconv.Itoa(row)
-	return &err
+	return err.withMetadata("row", strconv.Itoa(row))
 }
 
 // WithInfo adds additional information with "info" key to the AppError's metadata.
 func (err AppError) WithInfo(info string) *AppError {
-	err.Metadata["info"] = info
-	return &err
+	return err.withMetadata("info", info)
+}
+
+// WithMetadata adds an arbitrary key value to the AppError's metadata.
+func (err AppError) WithMetadata(key, value string) *AppError {
+	return err.withMetadata(key, value)
+}
+
+// WithDetails attaches a structured payload to the AppError, rendered under the "details" JSON key.
+func (err AppError) WithDetails(details any) *AppError {
+	e := err.clone()
+	e.Details = details
+	return e
+}
+
+// WithCauses sets the wrapped error to all causes, joined with errors.Join.
+func (err AppError) WithCauses(causes ...error) *AppError {
+	e := err.clone()
+	e.Err = errors.Join(causes...)
+	return e
+}
+
+// WithOp adds the name of the operation that failed, e.g. "service.GetUser".
+// Each call prepends to the path of operations, so the outermost operation comes first.
+func (err AppError) WithOp(op string) *AppError {
+	e := err.clone()
+	e.Ops = append([]string{op}, e.Ops...)
+	return e
+}
+
+// WithCategory re-categorizes the AppError. The Status field is only derived from the
+// new category if it was not set, so a custom status is preserved.
+func (err AppError) WithCategory(c Category) *AppError {
+	e := err.clone()
+	e.Code.Category = c
+	if e.Status == 0 {
+		e.Status = c.HTTPStatus()
+	}
+	return e
+}
+
+// WithStatusText sets a custom reason phrase for the HTTP status line.
+func (err AppError) WithStatusText(text string) *AppError {
+	e := err.clone()
+	e.StatusText = text
+	return e
+}
+
+// WithService sets the service name that namespaces the AppError's internal code.
+func (err AppError) WithService(name string) *AppError {
+	e := err.clone()
+	e.Code.Service = name
+	return e
+}
+
+// WithResource adds the name of a resource with "resource" key to the AppError's metadata.
+func (err AppError) WithResource(resource string) *AppError {
+	return err.withMetadata("resource", resource)
+}
+
+// WithRateLimit adds the rate limit, the remaining requests and the reset time to the AppError's metadata
+// with "rate_limit", "rate_limit_remaining" and "rate_limit_reset" keys. The reset time is stored in Unix seconds.
+func (err AppError) WithRateLimit(limit, remaining int, reset time.Time) *AppError {
+	return err.withMetadata("rate_limit", strconv.Itoa(limit)).
+		withMetadata("rate_limit_remaining", strconv.Itoa(remaining)).
+		withMetadata("rate_limit_reset", strconv.FormatInt(reset.Unix(), 10))
+}
+
+// WithLocation adds the URL of a related resource with "location" key to the AppError's metadata,
+// e.g. the existing resource of a 409 Conflict.
+func (err AppError) WithLocation(url string) *AppError {
+	return err.withMetadata("location", url)
+}
+
+// WithChallenge adds the authentication challenge of a 401 response with "challenge" key
+// to the AppError's metadata, e.g. `Bearer realm="api"`. The realm is omitted when empty.
+func (err AppError) WithChallenge(scheme, realm string) *AppError {
+	challenge := scheme
+	if realm != "" {
+		challenge += " realm=" + strconv.Quote(realm)
+	}
+	return err.withMetadata("challenge", challenge)
+}
+
+// WithRetry marks the AppError as Retryable and adds the delay after which the client may
+// retry, in whole seconds, with "retry_after" key to the AppError's metadata. The middleware
+// emits it as the Retry-After header.
+func (err AppError) WithRetry(after time.Duration) *AppError {
+	e := err.withMetadata("retry_after", strconv.FormatInt(int64(after/time.Second), 10))
+	e.Retryable = true
+	return e
+}
+
+// WithRequestID adds the request id with "request_id" key to the AppError's metadata.
+func (err AppError) WithRequestID(id string) *AppError {
+	return err.withMetadata("request_id", id)
+}
+
+// Equal reports whether other has the same wrapped error, status, code, message and metadata.
+// Wrapped errors are compared by identity.
+func (err AppError) Equal(other *AppError) bool {
+	return err.EqualIgnoringCause(other) && err.Err == other.Err
+}
+
+// EqualIgnoringCause reports whether other has the same status, code, message and metadata,
+// regardless of the wrapped error.
+func (err AppError) EqualIgnoringCause(other *AppError) bool {
+	if other == nil {
+		return false
+	}
+
+	return err.Status == other.Status &&
+		err.Code == other.Code &&
+		err.Message == other.Message &&
+		maps.Equal(err.Metadata, other.Metadata)
 }
 
 // IsCategory checks if the provided error belongs to the specified category.
@@ -121,6 +354,128 @@ func IsCategory(srcErr error, category Category) bool {
 	return false
 }
 
+// IsTimeout checks if the provided error chain contains a timeout.
+// It reports true for context.DeadlineExceeded, a net.Error whose Timeout method
+// returns true, or an AppError with the ErrTimeout category.
+func IsTimeout(srcErr error) bool {
+	if errors.Is(srcErr, context.DeadlineExceeded) {
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(srcErr, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	return IsCategory(srcErr, ErrTimeout)
+}
+
+// HTTPStatus returns the HTTP status code associated with the category.
+// Unknown categories map to 500 (Internal Server Error).
+func (c Category) HTTPStatus() int {
+	switch c {
+	case ErrValidation:
+		return http.StatusBadRequest
+	case ErrNotFound:
+		return http.StatusNotFound
+	case ErrMethoNotAllowed:
+		return http.StatusMethodNotAllowed
+	case ErrForbidden:
+		return http.StatusForbidden
+	case ErrUnauthorized:
+		return http.StatusUnauthorized
+	case ErrTimeout:
+		return http.StatusGatewayTimeout
+	case ErrCanceled:
+		return StatusClientClosedRequest
+	case ErrTooManyRequests:
+		return http.StatusTooManyRequests
+	case ErrConflict:
+		return http.StatusConflict
+	case ErrUnavailable:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// StatusForCategory returns the HTTP status code associated with the category.
+// It is equivalent to c.HTTPStatus().
+func StatusForCategory(c Category) int {
+	return c.HTTPStatus()
+}
+
+// CategoryForStatus returns the category that best matches the HTTP status code.
+// Unmapped 4xx codes map to ErrValidation and everything else maps to ErrInternal.
+func CategoryForStatus(status int) Category {
+	switch status {
+	case http.StatusBadRequest:
+		return ErrValidation
+	case http.StatusUnauthorized:
+		return ErrUnauthorized
+	case http.StatusForbidden:
+		return ErrForbidden
+	case http.StatusNotFound:
+		return ErrNotFound
+	case http.StatusMethodNotAllowed:
+		return ErrMethoNotAllowed
+	case StatusClientClosedRequest:
+		return ErrCanceled
+	case http.StatusTooManyRequests:
+		return ErrTooManyRequests
+	case http.StatusConflict:
+		return ErrConflict
+	case http.StatusServiceUnavailable:
+		return ErrUnavailable
+	case http.StatusGatewayTimeout:
+		return ErrTimeout
+	}
+
+	if status >= 400 && status < 500 {
+		return ErrValidation
+	}
+	return ErrInternal
+}
+
+// HTTPStatus returns the Status field if set, otherwise the HTTP status code of the error's category.
+func (err AppError) HTTPStatus() int {
+	if err.Status != 0 {
+		return err.Status
+	}
+	return err.Code.Category.HTTPStatus()
+}
+
+// PublicMessage returns the message meant for clients: the Message field, falling back
+// to the wrapped error message when it is empty.
+func (err AppError) PublicMessage() string {
+	if err.Message == "" && err.Err != nil {
+		return err.Err.Error()
+	}
+	return err.Message
+}
+
+// HTTPStatusText returns the StatusText field if set, otherwise the standard reason phrase of the error's HTTP status.
+func (err AppError) HTTPStatusText() string {
+	if err.StatusText != "" {
+		return err.StatusText
+	}
+	return http.StatusText(err.HTTPStatus())
+}
+
+// CacheControl returns the Cache-Control header value for the error response.
+// Server errors and authentication or authorization failures return "no-store",
+// while other errors return the empty string, leaving caching to the defaults.
+func (err AppError) CacheControl() string {
+	switch {
+	case err.HTTPStatus() >= http.StatusInternalServerError,
+		err.Code.Category == ErrUnauthorized,
+		err.Code.Category == ErrForbidden:
+		return "no-store"
+	default:
+		return ""
+	}
+}
+
 func (c Category) String() string {
 	switch c {
 	case ErrValidation:
@@ -131,24 +486,62 @@ func (c Category) String() string {
 		return "NotFouncError"
 	case ErrMethoNotAllowed:
 		return "MethoNotAllowedError"
+	case ErrSecurity:
+		return "SecurityError"
 	case ErrForbidden:
 		return "ForbiddenError"
 	case ErrUnauthorized:
 		return "UnauthorizedError"
+	case ErrTimeout:
+		return "TimeoutError"
+	case ErrCanceled:
+		return "CanceledError"
+	case ErrTooManyRequests:
+		return "TooManyRequestsError"
+	case ErrConflict:
+		return "ConflictError"
+	case ErrUnavailable:
+		return "UnavailableError"
 	default:
 		return "UnkownCategoryError"
 	}
 }
 
+// Unwrap returns the wrapped error. When Err was built with errors.Join or WithCauses,
+// errors.Is and errors.As traverse every joined cause through it.
 func (err AppError) Unwrap() error {
 	return err.Err
 }
 
-func withStatus(internalCode int, err error) *AppError {
+// withMetadata returns a copy of the AppError with the metadata key set, allocating the map on first use.
+func (err AppError) withMetadata(key, value string) *AppError {
+	e := err.clone()
+	if e.Metadata == nil {
+		e.Metadata = make(map[string]string)
+	}
+	e.Metadata[key] = value
+	return e
+}
+
+// clone returns a copy of the AppError that shares no mutable state with it, so that
+// every With* method can modify the copy without affecting the original.
+// Fields holding maps or slices must be copied here.
+func (err AppError) clone() *AppError {
+	err.Metadata = maps.Clone(err.Metadata)
+	err.Ops = slices.Clone(err.Ops)
+	err.Stack = slices.Clone(err.Stack)
+	err.Errors = slices.Clone(err.Errors)
+	return &err
+}
+
+// withCategory creates an AppError with the category and its HTTP status.
+// The internal code is left unset, as it is not related to the HTTP status.
+func withCategory(category Category, err error) *AppError {
 	return &AppError{
-		Err: err,
+		Err:    err,
+		Status: category.HTTPStatus(),
 		Code: Code{
-			Internal: internalCode,
+			Category: category,
 		},
 		Message: err.Error(),
 	}
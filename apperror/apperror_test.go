@@ -0,0 +1,1729 @@
+package apperror_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"text/template"
+	"time"
+
+	"github.com/ckminhano/golib/apperror"
+)
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func TestWithServiceDistinguishesCodes(t *testing.T) {
+	base := apperror.NewAppError(errors.New("boom"), apperror.ErrInternal, intPtr(1001))
+	billing := base.WithService("billing")
+	orders := base.WithService("orders")
+
+	if billing.Code == orders.Code {
+		t.Fatalf("expected codes to differ, both are %+v", billing.Code)
+	}
+	if got := billing.Code.String(); got != "billing/1001" {
+		t.Errorf("Code.String() = %q, want %q", got, "billing/1001")
+	}
+	if got := base.Code.String(); got != "1001" {
+		t.Errorf("Code.String() without service = %q, want %q", got, "1001")
+	}
+
+	billingJSON, err := json.Marshal(billing)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ordersJSON, err := json.Marshal(orders)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(billingJSON, ordersJSON) {
+		t.Fatalf("expected JSON to differ, both are %s", billingJSON)
+	}
+	if !strings.Contains(string(billingJSON), `"service":"billing"`) {
+		t.Errorf("JSON %s does not contain the service", billingJSON)
+	}
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, nil))
+	logger.Error("failed", "err", billing)
+	if !strings.Contains(buf.String(), "err.service=billing") {
+		t.Errorf("log output %q does not contain the service", buf.String())
+	}
+}
+
+func TestIsTimeout(t *testing.T) {
+	wrapped := fmt.Errorf("query users: %w", context.DeadlineExceeded)
+
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"deadline exceeded", context.DeadlineExceeded, true},
+		{"wrapped deadline exceeded", wrapped, true},
+		{"app error wrapping deadline", apperror.InternalServerError(wrapped), true},
+		{"timeout constructor", apperror.Timeout(errors.New("upstream slow")), true},
+		{"net timeout", &net.DNSError{IsTimeout: true}, true},
+		{"net non-timeout", &net.DNSError{}, false},
+		{"plain error", errors.New("boom"), false},
+		{"nil", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := apperror.IsTimeout(tt.err); got != tt.want {
+				t.Errorf("IsTimeout() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	err := apperror.Timeout(context.DeadlineExceeded)
+
+	if err.Status != http.StatusGatewayTimeout {
+		t.Errorf("Status = %d, want %d", err.Status, http.StatusGatewayTimeout)
+	}
+	if !apperror.IsCategory(err, apperror.ErrTimeout) {
+		t.Errorf("expected category %v, got %v", apperror.ErrTimeout, err.Code.Category)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Error("expected the wrapped error to be context.DeadlineExceeded")
+	}
+}
+
+func TestViewRendersInTemplate(t *testing.T) {
+	err := apperror.NewAppError(errors.New("name is required"), apperror.ErrValidation, intPtr(42)).
+		WithField("name").
+		WithInfo("must not be blank").
+		WithRequestID("req-1")
+
+	tmpl := template.Must(template.New("error").Parse(
+		"{{.Title}} {{.Code}}: {{.Message}} [field={{.Field}} info={{.Info}} request={{.RequestID}}]"))
+
+	var buf bytes.Buffer
+	if execErr := tmpl.Execute(&buf, err.View()); execErr != nil {
+		t.Fatal(execErr)
+	}
+
+	want := "invalid_request 42: name is required [field=name info=must not be blank request=req-1]"
+	if got := buf.String(); got != want {
+		t.Errorf("rendered %q, want %q", got, want)
+	}
+}
+
+func TestViewProduction(t *testing.T) {
+	view := apperror.NewAppError(errors.New("dial tcp 10.0.0.5:5432: password auth failed"), apperror.ErrInternal, nil).View()
+	want := apperror.ErrorView{Title: "server_error", Message: "Internal Server Error", Status: http.StatusInternalServerError, Code: "0"}
+	if !reflect.DeepEqual(view, want) {
+		t.Errorf("View() = %+v, want %+v", view, want)
+	}
+}
+
+func TestNilMetadataReaders(t *testing.T) {
+	err := apperror.NewAppError(errors.New("boom"), apperror.ErrInternal, nil)
+	if err.Metadata != nil {
+		t.Fatalf("expected metadata to be allocated lazily, got %v", err.Metadata)
+	}
+
+	if view := err.View(); view.Field != "" || view.Info != "" || view.RequestID != "" {
+		t.Errorf("View() = %+v, want empty metadata fields", view)
+	}
+	data, jsonErr := json.Marshal(err)
+	if jsonErr != nil {
+		t.Fatal(jsonErr)
+	}
+	if strings.Contains(string(data), "metadata") {
+		t.Errorf("JSON %s should omit empty metadata", data)
+	}
+	var buf bytes.Buffer
+	slog.New(slog.NewTextHandler(&buf, nil)).Error("failed", "err", err)
+	if strings.Contains(buf.String(), "metadata") {
+		t.Errorf("log output %q should omit empty metadata", buf.String())
+	}
+
+	withField := apperror.BadRequest(errors.New("bad")).WithField("name")
+	if got := withField.Metadata["field"]; got != "name" {
+		t.Errorf("field = %q, want %q", got, "name")
+	}
+}
+
+func BenchmarkNewAppErrorNoMetadata(b *testing.B) {
+	cause := errors.New("boom")
+	b.ReportAllocs()
+	for b.Loop() {
+		_ = apperror.NewAppError(cause, apperror.ErrValidation, nil)
+	}
+}
+
+func TestEqualIgnoringCause(t *testing.T) {
+	a := apperror.NewAppError(errors.New("first cause"), apperror.ErrNotFound, intPtr(7)).WithField("id")
+	b := apperror.NewAppError(errors.New("second cause"), apperror.ErrNotFound, intPtr(7)).WithField("id")
+
+	if !a.EqualIgnoringCause(b) {
+		t.Error("expected errors differing only by cause to be equal ignoring cause")
+	}
+	if a.Equal(b) {
+		t.Error("expected errors with different causes not to be strictly equal")
+	}
+	if !a.Equal(a) {
+		t.Error("expected an error to be strictly equal to itself")
+	}
+
+	c := apperror.NewAppError(errors.New("first cause"), apperror.ErrInternal, intPtr(7)).WithField("id")
+	if a.EqualIgnoringCause(c) {
+		t.Error("expected errors with different categories not to be equal")
+	}
+	if a.EqualIgnoringCause(nil) {
+		t.Error("expected comparison against nil to be false")
+	}
+}
+
+func TestMarshalEnvelopeJSON(t *testing.T) {
+	err := apperror.NewAppError(errors.New("boom"), apperror.ErrInternal, nil)
+
+	flat, jsonErr := json.Marshal(err)
+	if jsonErr != nil {
+		t.Fatal(jsonErr)
+	}
+	enveloped, jsonErr := err.MarshalEnvelopeJSON()
+	if jsonErr != nil {
+		t.Fatal(jsonErr)
+	}
+
+	want := `{"error":` + string(flat) + `}`
+	if string(enveloped) != want {
+		t.Errorf("MarshalEnvelopeJSON() = %s, want %s", enveloped, want)
+	}
+}
+
+func TestMarshalDebugJSONChain(t *testing.T) {
+	root := errors.New("connection refused")
+	repo := fmt.Errorf("repository: %w", root)
+	service := fmt.Errorf("service: %w", repo)
+	err := apperror.NewAppError(service, apperror.ErrInternal, nil)
+
+	data, jsonErr := err.MarshalDebugJSON()
+	if jsonErr != nil {
+		t.Fatal(jsonErr)
+	}
+
+	var got struct {
+		Chain []string `json:"chain"`
+	}
+	if jsonErr := json.Unmarshal(data, &got); jsonErr != nil {
+		t.Fatal(jsonErr)
+	}
+
+	want := []string{service.Error(), repo.Error(), root.Error()}
+	if !slices.Equal(got.Chain, want) {
+		t.Errorf("chain = %q, want %q", got.Chain, want)
+	}
+
+	flat, jsonErr := json.Marshal(err)
+	if jsonErr != nil {
+		t.Fatal(jsonErr)
+	}
+	if strings.Contains(string(flat), "chain") {
+		t.Errorf("MarshalJSON() = %s, should not include the chain", flat)
+	}
+}
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want apperror.Category
+	}{
+		{"not exist", fmt.Errorf("open config: %w", os.ErrNotExist), apperror.ErrNotFound},
+		{"permission", os.ErrPermission, apperror.ErrForbidden},
+		{"canceled", context.Canceled, apperror.ErrCanceled},
+		{"deadline exceeded", context.DeadlineExceeded, apperror.ErrTimeout},
+		{"eof", io.EOF, apperror.ErrInternal},
+		{"unknown", errors.New("boom"), apperror.ErrInternal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := apperror.Classify(tt.err)
+			if got.Code.Category != tt.want {
+				t.Errorf("category = %v, want %v", got.Code.Category, tt.want)
+			}
+			if got.Status != tt.want.HTTPStatus() {
+				t.Errorf("status = %d, want %d", got.Status, tt.want.HTTPStatus())
+			}
+			if !errors.Is(got, tt.err) {
+				t.Error("expected the classified error to wrap the original")
+			}
+		})
+	}
+
+	existing := apperror.NewAppError(errors.New("bad"), apperror.ErrValidation, nil)
+	if got := apperror.Classify(existing); got != existing {
+		t.Error("expected an AppError to be returned as is")
+	}
+	if apperror.Classify(nil) != nil {
+		t.Error("expected nil for a nil error")
+	}
+}
+
+func TestCategoryStatusRoundTrip(t *testing.T) {
+	for _, c := range apperror.AllCategories() {
+		status := apperror.StatusForCategory(c)
+		if status != c.HTTPStatus() {
+			t.Errorf("StatusForCategory(%v) = %d, want %d", c, status, c.HTTPStatus())
+		}
+
+		got := apperror.CategoryForStatus(status)
+		if got.HTTPStatus() != status {
+			t.Errorf("CategoryForStatus(%d) = %v, which maps back to %d", status, got, got.HTTPStatus())
+		}
+	}
+
+	if got := apperror.CategoryForStatus(http.StatusTeapot); got != apperror.ErrValidation {
+		t.Errorf("CategoryForStatus(418) = %v, want %v", got, apperror.ErrValidation)
+	}
+	if got := apperror.CategoryForStatus(http.StatusBadGateway); got != apperror.ErrInternal {
+		t.Errorf("CategoryForStatus(502) = %v, want %v", got, apperror.ErrInternal)
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	a := apperror.NewAppError(errors.New("user not found"), apperror.ErrNotFound, intPtr(3)).WithRequestID("req-1")
+	b := apperror.NewAppError(errors.New("user not found"), apperror.ErrNotFound, intPtr(3)).WithRequestID("req-2")
+
+	if a.Fingerprint() != b.Fingerprint() {
+		t.Errorf("expected identical errors to share a fingerprint, got %s and %s", a.Fingerprint(), b.Fingerprint())
+	}
+
+	c := apperror.NewAppError(errors.New("user not found"), apperror.ErrInternal, intPtr(3))
+	if a.Fingerprint() == c.Fingerprint() {
+		t.Error("expected different categories to produce different fingerprints")
+	}
+
+	d := apperror.NewAppError(errors.New("user not found"), apperror.ErrNotFound, intPtr(4))
+	if a.Fingerprint() == d.Fingerprint() {
+		t.Error("expected different internal codes to produce different fingerprints")
+	}
+}
+
+func TestWithDetails(t *testing.T) {
+	type input struct {
+		Name string `json:"name"`
+		Age  int    `json:"age"`
+	}
+	err := apperror.NewAppError(errors.New("invalid user"), apperror.ErrValidation, nil).
+		WithDetails(input{Name: "", Age: -1})
+
+	data, jsonErr := json.Marshal(err)
+	if jsonErr != nil {
+		t.Fatal(jsonErr)
+	}
+	if !strings.Contains(string(data), `"details":{"name":"","age":-1}`) {
+		t.Errorf("JSON %s does not contain the details", data)
+	}
+
+	unmarshalable := apperror.NewAppError(errors.New("invalid"), apperror.ErrValidation, nil).
+		WithDetails(make(chan int))
+	data, jsonErr = json.Marshal(unmarshalable)
+	if jsonErr != nil {
+		t.Fatalf("expected the marshal error to be recorded, got %v", jsonErr)
+	}
+	if !strings.Contains(string(data), `"details_error"`) {
+		t.Errorf("JSON %s does not record the details marshal error", data)
+	}
+}
+
+func TestAllCategories(t *testing.T) {
+	categories := apperror.AllCategories()
+	if got, want := len(categories), int(apperror.ErrUnavailable)+1; got != want {
+		t.Fatalf("len(AllCategories()) = %d, want %d", got, want)
+	}
+
+	seen := make(map[string]bool)
+	for i, c := range categories {
+		if int(c) != i {
+			t.Errorf("category at index %d is %d, want declaration order", i, c)
+		}
+		name := c.String()
+		if name == "" || name == "UnkownCategoryError" {
+			t.Errorf("category %d has no name", c)
+		}
+		if seen[name] {
+			t.Errorf("category name %q is duplicated", name)
+		}
+		seen[name] = true
+		if c.HTTPStatus() == 0 {
+			t.Errorf("category %v has no HTTP status", c)
+		}
+	}
+}
+
+func TestBuild(t *testing.T) {
+	if _, err := apperror.Build(apperror.WithCategory(apperror.ErrValidation)); !errors.Is(err, apperror.ErrIncomplete) {
+		t.Errorf("Build() without cause or message error = %v, want %v", err, apperror.ErrIncomplete)
+	}
+
+	cause := errors.New("name is required")
+	got, err := apperror.Build(
+		apperror.WithCause(cause),
+		apperror.WithCategory(apperror.ErrValidation),
+		apperror.WithCode(12),
+		apperror.WithStatus(http.StatusUnprocessableEntity),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !errors.Is(got, cause) || got.Code.Category != apperror.ErrValidation || got.Code.Internal != 12 || got.Status != http.StatusUnprocessableEntity {
+		t.Errorf("Build() = %+v", got)
+	}
+
+	messageOnly, err := apperror.Build(apperror.WithMessage("something went wrong"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := messageOnly.Error(); got != "something went wrong" {
+		t.Errorf("Error() = %q, want the message", got)
+	}
+}
+
+func TestWithOp(t *testing.T) {
+	base := apperror.NewAppError(errors.New("not found"), apperror.ErrNotFound, nil)
+
+	inner := base.WithOp("service.GetUser")
+	if got, want := inner.Error(), "service.GetUser: not found"; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+
+	outer := inner.WithOp("handler.Get")
+	if got, want := outer.Error(), "handler.Get -> service.GetUser: not found"; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+	if got, want := inner.Error(), "service.GetUser: not found"; got != want {
+		t.Errorf("inner Error() changed to %q, want %q", got, want)
+	}
+	if got, want := base.Error(), "not found"; got != want {
+		t.Errorf("base Error() changed to %q, want %q", got, want)
+	}
+}
+
+func TestProblemJSONType(t *testing.T) {
+	apperror.TypeURIForCategory[apperror.ErrValidation] = "https://docs.example.com/errors/validation"
+	t.Cleanup(func() { delete(apperror.TypeURIForCategory, apperror.ErrValidation) })
+
+	tests := []struct {
+		name string
+		err  *apperror.AppError
+		want string
+	}{
+		{"mapped", apperror.NewAppError(errors.New("bad"), apperror.ErrValidation, nil), "https://docs.example.com/errors/validation"},
+		{"fallback", apperror.NewAppError(errors.New("gone"), apperror.ErrNotFound, nil), "/errors/not_found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := tt.err.ProblemJSON()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got struct {
+				Type   string `json:"type"`
+				Status int    `json:"status"`
+				Detail string `json:"detail"`
+			}
+			if err := json.Unmarshal(data, &got); err != nil {
+				t.Fatal(err)
+			}
+			if got.Type != tt.want {
+				t.Errorf("type = %q, want %q", got.Type, tt.want)
+			}
+			if got.Status != tt.err.HTTPStatus() || got.Detail != tt.err.Error() {
+				t.Errorf("problem = %+v", got)
+			}
+		})
+	}
+}
+
+func TestHTTPStatusText(t *testing.T) {
+	err := apperror.NewAppError(errors.New("gone"), apperror.ErrNotFound, nil)
+	if got := err.HTTPStatusText(); got != http.StatusText(http.StatusNotFound) {
+		t.Errorf("HTTPStatusText() = %q, want the standard reason phrase", got)
+	}
+	if got := err.WithStatusText("No Such Thing").HTTPStatusText(); got != "No Such Thing" {
+		t.Errorf("HTTPStatusText() = %q, want the custom reason phrase", got)
+	}
+}
+
+func TestWithCategory(t *testing.T) {
+	zeroStatus := apperror.NewAppError(errors.New("missing"), apperror.ErrInternal, nil).WithCategory(apperror.ErrNotFound)
+	if zeroStatus.Code.Category != apperror.ErrNotFound {
+		t.Errorf("category = %v, want %v", zeroStatus.Code.Category, apperror.ErrNotFound)
+	}
+	if zeroStatus.Status != http.StatusNotFound {
+		t.Errorf("status = %d, want the derived %d", zeroStatus.Status, http.StatusNotFound)
+	}
+
+	custom := apperror.New(
+		apperror.WithCause(errors.New("missing")),
+		apperror.WithCategory(apperror.ErrInternal),
+		apperror.WithStatus(http.StatusGone),
+	).WithCategory(apperror.ErrNotFound)
+	if custom.Code.Category != apperror.ErrNotFound {
+		t.Errorf("category = %v, want %v", custom.Code.Category, apperror.ErrNotFound)
+	}
+	if custom.Status != http.StatusGone {
+		t.Errorf("status = %d, want the preserved %d", custom.Status, http.StatusGone)
+	}
+}
+
+func TestWriteToReadFromPipe(t *testing.T) {
+	want := apperror.NewAppError(errors.New("user not found"), apperror.ErrNotFound, intPtr(404)).
+		WithService("users").
+		WithField("id").
+		WithOp("users.Get")
+	want.Severity = apperror.SeverityCritical
+	want.Retryable = true
+	multi := apperror.NewMultiError(apperror.ErrValidation,
+		apperror.BadRequest(errors.New("name is required")).WithField("name"),
+		apperror.BadRequest(errors.New("email is invalid")).WithField("email"),
+	)
+
+	pr, pw := io.Pipe()
+	go func() {
+		// Write byte by byte to force partial reads on the other side.
+		w := writerFunc(func(p []byte) (int, error) {
+			for i := range p {
+				if _, err := pw.Write(p[i : i+1]); err != nil {
+					return i, err
+				}
+			}
+			return len(p), nil
+		})
+		_, err := want.WriteTo(w)
+		if err == nil {
+			_, err = multi.WriteTo(w)
+		}
+		pw.CloseWithError(err)
+	}()
+
+	var firstRead, multiRead, lastRead apperror.AppError
+	n, err := firstRead.ReadFrom(pr)
+	if err != nil {
+		t.Fatalf("ReadFrom() unexpected error: %v", err)
+	}
+	if n <= 4 {
+		t.Errorf("ReadFrom() read %d bytes", n)
+	}
+	if firstRead.Code != want.Code || firstRead.Status != want.Status || !maps.Equal(firstRead.Metadata, want.Metadata) {
+		t.Errorf("ReadFrom() = %#v, want %#v", firstRead, *want)
+	}
+	if firstRead.Error() != want.Error() {
+		t.Errorf("Error() = %q, want %q", firstRead.Error(), want.Error())
+	}
+	if firstRead.Severity != want.Severity || firstRead.Retryable != want.Retryable || !slices.Equal(firstRead.Ops, want.Ops) {
+		t.Errorf("ReadFrom() severity = %v, retryable = %v, ops = %v, want %v, %v, %v",
+			firstRead.Severity, firstRead.Retryable, firstRead.Ops, want.Severity, want.Retryable, want.Ops)
+	}
+
+	if _, err := multiRead.ReadFrom(pr); err != nil {
+		t.Fatalf("ReadFrom() of a multi-error unexpected error: %v", err)
+	}
+	if len(multiRead.Errors) != len(multi.Errors) {
+		t.Fatalf("ReadFrom() read %d sub-errors, want %d", len(multiRead.Errors), len(multi.Errors))
+	}
+	for i, sub := range multi.Errors {
+		got := multiRead.Errors[i]
+		if got.Code != sub.Code || got.PublicMessage() != sub.PublicMessage() || !maps.Equal(got.Metadata, sub.Metadata) {
+			t.Errorf("sub-error %d = %#v, want %#v", i, *got, *sub)
+		}
+	}
+
+	if _, err := lastRead.ReadFrom(pr); !errors.Is(err, io.EOF) {
+		t.Errorf("ReadFrom() at end of stream error = %v, want io.EOF", err)
+	}
+}
+
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) {
+	return f(p)
+}
+
+func TestValidate(t *testing.T) {
+	cause := errors.New("boom")
+
+	tests := []struct {
+		name    string
+		err     *apperror.AppError
+		wantErr bool
+	}{
+		{"validation with field", apperror.NewAppError(cause, apperror.ErrValidation, nil).WithField("name"), false},
+		{"validation without field", apperror.NewAppError(cause, apperror.ErrValidation, nil), true},
+		{"not found with resource", apperror.NewAppError(cause, apperror.ErrNotFound, nil).WithResource("user"), false},
+		{"not found without resource", apperror.NewAppError(cause, apperror.ErrNotFound, nil).WithField("id"), true},
+		{"internal", apperror.NewAppError(cause, apperror.ErrInternal, nil), false},
+		{"builder multi-error", apperror.NewValidationBuilder().AddField("name", "required").AddField("age", "too low").Err(), false},
+		{"proto validate multi-error", apperror.FromProtoValidate([]apperror.Violation{{FieldPath: "email", Constraint: "string.email", Message: "invalid"}}), false},
+		{"multi-error with a sub-error without field", apperror.NewMultiError(apperror.ErrValidation,
+			apperror.NewAppError(cause, apperror.ErrValidation, nil).WithField("name"),
+			apperror.NewAppError(cause, apperror.ErrValidation, nil)), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.err.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestJoin(t *testing.T) {
+	notFound := apperror.NewAppError(errors.New("missing"), apperror.ErrNotFound, nil)
+	validation := apperror.NewAppError(errors.New("bad"), apperror.ErrValidation, nil)
+
+	multi := apperror.Join(validation, nil, notFound)
+	if len(multi.Errors) != 2 {
+		t.Fatalf("len(Errors) = %d, want 2", len(multi.Errors))
+	}
+	if multi.Code.Category != apperror.ErrNotFound || multi.Status != http.StatusNotFound {
+		t.Errorf("category = %v status = %d, want the highest-status sub-error", multi.Code.Category, multi.Status)
+	}
+	if !errors.Is(multi, notFound) || !errors.Is(multi, validation) {
+		t.Error("expected errors.Is to find every sub-error")
+	}
+
+	withPlain := apperror.Join(validation, errors.New("boom"))
+	if withPlain.Code.Category != apperror.ErrInternal {
+		t.Errorf("category = %v, want %v", withPlain.Code.Category, apperror.ErrInternal)
+	}
+	if apperror.Join(nil, nil) != nil {
+		t.Error("expected nil when every error is nil")
+	}
+}
+
+func TestSlogAttrs(t *testing.T) {
+	err := apperror.NewAppError(errors.New("boom"), apperror.ErrInternal, intPtr(9)).WithField("name")
+
+	got := make(map[string]string)
+	for _, attr := range err.SlogAttrs("err") {
+		got[attr.Key] = attr.Value.String()
+	}
+
+	want := map[string]string{
+		"err.category":       "InternalError",
+		"err.status":         "500",
+		"err.code":           "9",
+		"err.error":          "boom",
+		"err.metadata.field": "name",
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("attr %q = %q, want %q", k, got[k], v)
+		}
+	}
+}
+
+func TestMaxMetadataBytes(t *testing.T) {
+	previous := apperror.MaxMetadataBytes
+	apperror.MaxMetadataBytes = 64
+	t.Cleanup(func() { apperror.MaxMetadataBytes = previous })
+
+	small := apperror.NewAppError(errors.New("bad"), apperror.ErrValidation, nil).WithField("name")
+	data, err := json.Marshal(small)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "metadata_truncated") {
+		t.Errorf("JSON %s should not be truncated", data)
+	}
+
+	huge := small.WithInfo(strings.Repeat("x", 100))
+	for name, marshal := range map[string]func() ([]byte, error){
+		"MarshalJSON": huge.MarshalJSON,
+		"ProblemJSON": huge.ProblemJSON,
+	} {
+		data, err := marshal()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got struct {
+			Metadata  map[string]string `json:"metadata"`
+			Truncated bool              `json:"metadata_truncated"`
+		}
+		if err := json.Unmarshal(data, &got); err != nil {
+			t.Fatal(err)
+		}
+		if !got.Truncated {
+			t.Errorf("%s: expected metadata_truncated in %s", name, data)
+		}
+		if _, ok := got.Metadata["info"]; ok || got.Metadata["field"] != "name" {
+			t.Errorf("%s: metadata = %v, want only the entries fitting the limit", name, got.Metadata)
+		}
+	}
+}
+
+func TestExcludeFromErrorBudget(t *testing.T) {
+	notFound := apperror.NewAppError(errors.New("missing"), apperror.ErrNotFound, nil)
+	tooMany := apperror.TooManyRequests(errors.New("slow down"))
+	internal := apperror.NewAppError(errors.New("boom"), apperror.ErrInternal, nil)
+
+	if !apperror.ExcludeFromErrorBudget(notFound) || !apperror.ExcludeFromErrorBudget(tooMany) {
+		t.Error("expected the default policy to exclude client errors")
+	}
+	if apperror.ExcludeFromErrorBudget(internal) || apperror.ExcludeFromErrorBudget(errors.New("plain")) {
+		t.Error("expected the default policy to count server and plain errors")
+	}
+
+	apperror.SetErrorBudgetPolicy(func(err *apperror.AppError) bool {
+		return err.Code.Category == apperror.ErrNotFound
+	})
+	t.Cleanup(func() { apperror.SetErrorBudgetPolicy(nil) })
+
+	if !apperror.ExcludeFromErrorBudget(notFound) {
+		t.Error("expected the custom policy to exclude 404")
+	}
+	if apperror.ExcludeFromErrorBudget(tooMany) {
+		t.Error("expected the custom policy to count 429")
+	}
+}
+
+func TestEscalate(t *testing.T) {
+	err := apperror.NewAppError(errors.New("transient"), apperror.ErrInternal, nil)
+	if got := err.EffectiveSeverity(); got != apperror.SeverityError {
+		t.Fatalf("EffectiveSeverity() = %v, want %v", got, apperror.SeverityError)
+	}
+
+	below := apperror.Escalate(err, apperror.EscalationThreshold-1)
+	if got := below.EffectiveSeverity(); got != apperror.SeverityError {
+		t.Errorf("below threshold EffectiveSeverity() = %v, want %v", got, apperror.SeverityError)
+	}
+
+	at := apperror.Escalate(err, apperror.EscalationThreshold)
+	if got := at.EffectiveSeverity(); got != apperror.SeverityCritical {
+		t.Errorf("at threshold EffectiveSeverity() = %v, want %v", got, apperror.SeverityCritical)
+	}
+	if got := err.EffectiveSeverity(); got != apperror.SeverityError {
+		t.Errorf("Escalate changed the original severity to %v", got)
+	}
+	if got := apperror.Escalate(nil, apperror.EscalationThreshold); got != nil {
+		t.Errorf("Escalate(nil) = %v, want nil", got)
+	}
+}
+
+func TestSetCategorySeverity(t *testing.T) {
+	apperror.SetCategorySeverity(apperror.ErrNotFound, apperror.SeverityInfo)
+	t.Cleanup(func() { apperror.SetCategorySeverity(apperror.ErrNotFound, 0) })
+
+	err := apperror.NotFound(errors.New("missing"))
+	if got := err.EffectiveSeverity(); got != apperror.SeverityInfo {
+		t.Errorf("EffectiveSeverity() = %v, want %v", got, apperror.SeverityInfo)
+	}
+	if got := err.WithSeverity(apperror.SeverityCritical).EffectiveSeverity(); got != apperror.SeverityCritical {
+		t.Errorf("WithSeverity EffectiveSeverity() = %v, want %v", got, apperror.SeverityCritical)
+	}
+	if got := apperror.ErrValidation.Severity(); got != apperror.SeverityWarning {
+		t.Errorf("ErrValidation.Severity() = %v, want %v", got, apperror.SeverityWarning)
+	}
+
+	apperror.SetCategorySeverity(apperror.ErrNotFound, 0)
+	if got := err.EffectiveSeverity(); got != apperror.SeverityWarning {
+		t.Errorf("after reset EffectiveSeverity() = %v, want %v", got, apperror.SeverityWarning)
+	}
+}
+
+func TestFromProtoValidate(t *testing.T) {
+	err := apperror.FromProtoValidate([]apperror.Violation{
+		{FieldPath: "user.email", Constraint: "string.email", Message: "value must be a valid email address"},
+		{FieldPath: "user.age", Constraint: "int32.gte", Message: "value must be greater than or equal to 0"},
+	})
+
+	if err.Code.Category != apperror.ErrValidation || err.HTTPStatus() != http.StatusBadRequest {
+		t.Errorf("category = %v status = %d, want a validation error", err.Code.Category, err.HTTPStatus())
+	}
+	if len(err.Errors) != 2 {
+		t.Fatalf("len(Errors) = %d, want 2", len(err.Errors))
+	}
+
+	first := err.Errors[0]
+	if first.Metadata["field"] != "user.email" || first.Metadata["constraint"] != "string.email" || first.Error() != "value must be a valid email address" {
+		t.Errorf("first violation = %+v", first)
+	}
+	if err.Errors[1].Metadata["field"] != "user.age" {
+		t.Errorf("second violation field = %q", err.Errors[1].Metadata["field"])
+	}
+	if apperror.FromProtoValidate(nil) != nil {
+		t.Error("expected nil without violations")
+	}
+}
+
+func TestHelperConstructorsStatus(t *testing.T) {
+	cause := errors.New("boom")
+
+	tests := []struct {
+		name string
+		err  *apperror.AppError
+		want int
+	}{
+		{"BadRequest", apperror.BadRequest(cause), http.StatusBadRequest},
+		{"NotFound", apperror.NotFound(cause), http.StatusNotFound},
+		{"Unauthorized", apperror.Unauthorized(cause), http.StatusUnauthorized},
+		{"Forbidden", apperror.Forbidden(cause), http.StatusForbidden},
+		{"InternalServerError", apperror.InternalServerError(cause), http.StatusInternalServerError},
+		{"MethodNotAllowed", apperror.MethodNotAllowed(cause), http.StatusMethodNotAllowed},
+		{"Timeout", apperror.Timeout(cause), http.StatusGatewayTimeout},
+		{"TooManyRequests", apperror.TooManyRequests(cause), http.StatusTooManyRequests},
+		{"Conflict", apperror.Conflict(cause), http.StatusConflict},
+		{"Unavailable", apperror.Unavailable(cause), http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.err.HTTPStatus(); got != tt.want {
+				t.Errorf("HTTPStatus() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+
+	unknown := apperror.NewAppError(cause, apperror.Category(99), nil)
+	if got := unknown.HTTPStatus(); got != http.StatusInternalServerError {
+		t.Errorf("unknown category HTTPStatus() = %d, want %d", got, http.StatusInternalServerError)
+	}
+}
+
+func TestHelperConstructorsFields(t *testing.T) {
+	cause := errors.New("boom")
+
+	tests := []struct {
+		name     string
+		err      *apperror.AppError
+		status   int
+		category apperror.Category
+	}{
+		{"BadRequest", apperror.BadRequest(cause), http.StatusBadRequest, apperror.ErrValidation},
+		{"NotFound", apperror.NotFound(cause), http.StatusNotFound, apperror.ErrNotFound},
+		{"Unauthorized", apperror.Unauthorized(cause), http.StatusUnauthorized, apperror.ErrUnauthorized},
+		{"Forbidden", apperror.Forbidden(cause), http.StatusForbidden, apperror.ErrForbidden},
+		{"InternalServerError", apperror.InternalServerError(cause), http.StatusInternalServerError, apperror.ErrInternal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.err.Status != tt.status {
+				t.Errorf("Status = %d, want %d", tt.err.Status, tt.status)
+			}
+			if tt.err.Code.Category != tt.category {
+				t.Errorf("Code.Category = %v, want %v", tt.err.Code.Category, tt.category)
+			}
+			if tt.err.Code.Internal != 0 {
+				t.Errorf("Code.Internal = %d, want 0", tt.err.Code.Internal)
+			}
+		})
+	}
+}
+
+func TestHelperConstructorsIsCategory(t *testing.T) {
+	cause := errors.New("boom")
+
+	tests := []struct {
+		name     string
+		err      error
+		category apperror.Category
+	}{
+		{"BadRequest", apperror.BadRequest(cause), apperror.ErrValidation},
+		{"NotFound", apperror.NotFound(cause), apperror.ErrNotFound},
+		{"Unauthorized", apperror.Unauthorized(cause), apperror.ErrUnauthorized},
+		{"Forbidden", apperror.Forbidden(cause), apperror.ErrForbidden},
+		{"InternalServerError", apperror.InternalServerError(cause), apperror.ErrInternal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !apperror.IsCategory(tt.err, tt.category) {
+				t.Errorf("IsCategory(%v) = false, want true", tt.category)
+			}
+			if wrapped := fmt.Errorf("handler: %w", tt.err); !apperror.IsCategory(wrapped, tt.category) {
+				t.Errorf("IsCategory(%v) on a wrapped error = false, want true", tt.category)
+			}
+		})
+	}
+}
+
+func TestWithMethodsCopyOnWrite(t *testing.T) {
+	type state struct {
+		err      *apperror.AppError
+		metadata map[string]string
+		ops      []string
+	}
+
+	type step struct {
+		name  string
+		apply func(err *apperror.AppError, v string) *apperror.AppError
+		key   string
+	}
+	steps := []step{
+		{"WithField", (*apperror.AppError).WithField, "field"},
+		{"WithInfo", (*apperror.AppError).WithInfo, "info"},
+		{"WithRequestID", (*apperror.AppError).WithRequestID, "request_id"},
+		{"WithResource", (*apperror.AppError).WithResource, "resource"},
+		{"WithOp", (*apperror.AppError).WithOp, ""},
+	}
+
+	rng := rand.New(rand.NewPCG(1, 2))
+	for _, withBaseMetadata := range []bool{false, true} {
+		base := apperror.NewAppError(errors.New("boom"), apperror.ErrValidation, nil)
+		baseMetadata := map[string]string{}
+		if withBaseMetadata {
+			base = base.WithField("base")
+			baseMetadata["field"] = "base"
+		}
+
+		states := []state{{err: base, metadata: baseMetadata}}
+		for i := range 200 {
+			parent := states[rng.IntN(len(states))]
+			s := steps[rng.IntN(len(steps))]
+			value := fmt.Sprintf("%s-%d", s.name, i)
+
+			child := state{
+				err:      s.apply(parent.err, value),
+				metadata: maps.Clone(parent.metadata),
+				ops:      slices.Clone(parent.ops),
+			}
+			if s.key != "" {
+				child.metadata[s.key] = value
+			} else {
+				child.ops = append([]string{value}, child.ops...)
+			}
+			states = append(states, child)
+		}
+
+		for i, s := range states {
+			got := s.err.Metadata
+			if got == nil {
+				got = map[string]string{}
+			}
+			if !maps.Equal(got, s.metadata) {
+				t.Fatalf("branch %d metadata = %v, want %v", i, got, s.metadata)
+			}
+			if !slices.Equal(s.err.Ops, s.ops) {
+				t.Fatalf("branch %d ops = %v, want %v", i, s.err.Ops, s.ops)
+			}
+		}
+	}
+}
+
+func TestRenderMessage(t *testing.T) {
+	err := apperror.New(
+		apperror.WithCategory(apperror.ErrNotFound),
+		apperror.WithMessage("{resource} {id} not found, see {docs}"),
+	).WithResource("user")
+	err.Metadata["id"] = "42"
+
+	want := "user 42 not found, see {docs}"
+	if got := err.RenderMessage(); got != want {
+		t.Errorf("RenderMessage() = %q, want %q", got, want)
+	}
+}
+
+func TestDedupe(t *testing.T) {
+	required := func(field string) *apperror.AppError {
+		return apperror.NewAppError(errors.New("is required"), apperror.ErrValidation, nil).WithField(field)
+	}
+
+	multi := apperror.NewMultiError(apperror.ErrValidation, required("name"), required("email"), required("name"))
+	deduped := multi.Dedupe()
+
+	if len(deduped.Errors) != 2 {
+		t.Fatalf("len(Errors) = %d, want 2", len(deduped.Errors))
+	}
+	if deduped.Errors[0].Metadata["field"] != "name" || deduped.Errors[1].Metadata["field"] != "email" {
+		t.Errorf("kept fields %q and %q, want name and email", deduped.Errors[0].Metadata["field"], deduped.Errors[1].Metadata["field"])
+	}
+	if deduped.Message != "2 errors occurred" {
+		t.Errorf("Message = %q, want the updated count", deduped.Message)
+	}
+	if len(multi.Errors) != 3 {
+		t.Errorf("Dedupe changed the original to %d errors", len(multi.Errors))
+	}
+
+	single := apperror.NewMultiError(apperror.ErrValidation, required("name"), required("name")).Dedupe()
+	if single.Message != "1 error occurred" {
+		t.Errorf("Message = %q, want \"1 error occurred\"", single.Message)
+	}
+}
+
+func TestRegisterCode(t *testing.T) {
+	const userNotFound apperror.ErrorCode = 4040
+	apperror.RegisterCode(userNotFound, "user_not_found")
+	t.Cleanup(func() { apperror.UnregisterCode(userNotFound) })
+
+	err := apperror.NewAppError(errors.New("user not found"), apperror.ErrNotFound, intPtr(4040))
+	if got := err.Code.Name(); got != "user_not_found" {
+		t.Errorf("Code.Name() = %q, want %q", got, "user_not_found")
+	}
+	if got := apperror.ErrorCode(1).Name(); got != "" {
+		t.Errorf("unregistered Name() = %q, want empty", got)
+	}
+
+	data, jsonErr := json.Marshal(err)
+	if jsonErr != nil {
+		t.Fatal(jsonErr)
+	}
+	if !strings.Contains(string(data), `"code":4040,"code_name":"user_not_found"`) {
+		t.Errorf("JSON %s does not contain the named code", data)
+	}
+}
+
+func TestWriteTrailer(t *testing.T) {
+	rec := httptest.NewRecorder()
+	apperror.DeclareTrailers(rec)
+	rec.WriteHeader(http.StatusOK)
+	_, _ = rec.Write([]byte(`{"partial":`))
+
+	apperror.Timeout(errors.New("upstream 10.0.0.5 stalled")).WriteTrailer(rec)
+
+	trailer := rec.Result().Trailer
+	want := map[string]string{
+		apperror.TrailerCategory: "timeout",
+		apperror.TrailerStatus:   "504",
+		apperror.TrailerMessage:  "Gateway Timeout",
+	}
+	for k, v := range want {
+		if got := trailer.Get(k); got != v {
+			t.Errorf("trailer %s = %q, want %q", k, got, v)
+		}
+	}
+}
+
+func TestShouldLog(t *testing.T) {
+	apperror.SampleRate[apperror.ErrValidation] = 0
+	apperror.SampleRate[apperror.ErrInternal] = 1
+	previous := apperror.SampleRandom
+	t.Cleanup(func() {
+		delete(apperror.SampleRate, apperror.ErrValidation)
+		delete(apperror.SampleRate, apperror.ErrInternal)
+		apperror.SampleRandom = previous
+	})
+
+	validation := apperror.NewAppError(errors.New("bad"), apperror.ErrValidation, nil)
+	internal := apperror.NewAppError(errors.New("boom"), apperror.ErrInternal, nil)
+	notFound := apperror.NewAppError(errors.New("missing"), apperror.ErrNotFound, nil)
+
+	for _, r := range []float64{0, 0.5, 0.999} {
+		apperror.SampleRandom = func() float64 { return r }
+		if apperror.ShouldLog(validation) {
+			t.Errorf("rate 0 logged with random %v", r)
+		}
+		if !apperror.ShouldLog(internal) {
+			t.Errorf("rate 1 did not log with random %v", r)
+		}
+		if !apperror.ShouldLog(notFound) {
+			t.Errorf("category without a rate did not log with random %v", r)
+		}
+	}
+}
+
+func TestWithCauses(t *testing.T) {
+	errDisk := errors.New("disk full")
+	errQuota := errors.New("quota exceeded")
+	errOther := errors.New("other")
+
+	err := apperror.InternalServerError(errors.New("save failed")).WithCauses(errDisk, errQuota)
+
+	for _, cause := range []error{errDisk, errQuota} {
+		if !errors.Is(err, cause) {
+			t.Errorf("errors.Is(err, %q) = false, want true", cause)
+		}
+	}
+	if errors.Is(err, errOther) {
+		t.Error("errors.Is(err, other) = true, want false")
+	}
+
+	cause := errors.New("root")
+	if got := errors.Unwrap(apperror.InternalServerError(cause)); got != cause {
+		t.Errorf("errors.Unwrap() = %v, want the wrapped error", got)
+	}
+
+	var target *os.PathError
+	pathErr := &os.PathError{Op: "open", Path: "/tmp/x", Err: os.ErrNotExist}
+	if !errors.As(err.WithCauses(errDisk, pathErr), &target) || target != pathErr {
+		t.Error("errors.As did not find the second cause")
+	}
+}
+
+func TestMarshalJSONMode(t *testing.T) {
+	t.Cleanup(func() { apperror.Mode = apperror.ModeProduction })
+
+	err := apperror.Recovered(errors.New("nil pointer in db.Query"))
+
+	apperror.Mode = apperror.ModeProduction
+	prod, marshalErr := json.Marshal(err)
+	if marshalErr != nil {
+		t.Fatal(marshalErr)
+	}
+	for _, internal := range []string{"db.Query", `"stack"`, `"cause"`} {
+		if strings.Contains(string(prod), internal) {
+			t.Errorf("production output %s contains %s", prod, internal)
+		}
+	}
+	if !strings.Contains(string(prod), `"message":"Internal Server Error"`) || !strings.Contains(string(prod), `"category":"server_error"`) {
+		t.Errorf("production output %s lacks the public message and category", prod)
+	}
+
+	apperror.Mode = apperror.ModeDevelopment
+	dev, marshalErr := json.Marshal(err)
+	if marshalErr != nil {
+		t.Fatal(marshalErr)
+	}
+	for _, internal := range []string{`"cause":"nil pointer in db.Query"`, `"stack":"`, "TestMarshalJSONMode"} {
+		if !strings.Contains(string(dev), internal) {
+			t.Errorf("development output %s lacks %s", dev, internal)
+		}
+	}
+}
+
+func TestPublicCategory(t *testing.T) {
+	tests := []struct {
+		category apperror.Category
+		want     string
+	}{
+		{apperror.ErrInternal, "server_error"},
+		{apperror.ErrSecurity, "server_error"},
+		{apperror.ErrValidation, "invalid_request"},
+		{apperror.ErrMethoNotAllowed, "invalid_request"},
+		{apperror.ErrNotFound, "not_found"},
+		{apperror.ErrTooManyRequests, "rate_limited"},
+	}
+	for _, tt := range tests {
+		err := apperror.NewAppError(errors.New("oops"), tt.category, nil)
+		if got := err.PublicCategory(); got != tt.want {
+			t.Errorf("%v.PublicCategory() = %q, want %q", tt.category, got, tt.want)
+		}
+	}
+
+	security := apperror.NewAppError(errors.New("token replayed"), apperror.ErrSecurity, nil)
+	data, marshalErr := json.Marshal(security)
+	if marshalErr != nil {
+		t.Fatal(marshalErr)
+	}
+	if strings.Contains(string(data), "SecurityError") {
+		t.Errorf("production output %s exposes the internal category", data)
+	}
+
+	var decoded apperror.AppError
+	if unmarshalErr := json.Unmarshal(data, &decoded); unmarshalErr != nil {
+		t.Fatal(unmarshalErr)
+	}
+	if decoded.Code.Category != apperror.ErrInternal {
+		t.Errorf("decoded category = %v, want %v", decoded.Code.Category, apperror.ErrInternal)
+	}
+}
+
+func TestJSONSchema(t *testing.T) {
+	schema := apperror.JSONSchema()
+	if _, err := json.Marshal(schema); err != nil {
+		t.Fatalf("schema does not marshal: %v", err)
+	}
+
+	required, _ := schema["required"].([]string)
+	for _, field := range []string{"message", "category"} {
+		if !slices.Contains(required, field) {
+			t.Errorf("required = %v, want it to contain %q", required, field)
+		}
+	}
+
+	properties := schema["properties"].(map[string]any)
+	enum := properties["category"].(map[string]any)["enum"].([]string)
+	for _, c := range apperror.AllCategories() {
+		if !slices.Contains(enum, c.String()) {
+			t.Errorf("category enum %v lacks %q", enum, c)
+		}
+	}
+	err := apperror.NewAppError(errors.New("oops"), apperror.ErrSecurity, nil)
+	if !slices.Contains(enum, err.PublicCategory()) {
+		t.Errorf("category enum %v lacks the public name %q", enum, err.PublicCategory())
+	}
+}
+
+func TestFormatStack(t *testing.T) {
+	err := apperror.InternalServerError(errors.New("boom")).WithStack()
+
+	trace := err.FormatStack()
+	lines := strings.Split(strings.TrimSuffix(trace, "\n"), "\n")
+	if !strings.Contains(lines[0], "apperror_test.go:") || !strings.HasSuffix(lines[0], ".TestFormatStack") {
+		t.Errorf("first frame = %q, want the test function", lines[0])
+	}
+	for _, line := range lines {
+		if strings.Contains(line, " runtime.") {
+			t.Errorf("trace contains runtime frame %q", line)
+		}
+	}
+
+	previous := apperror.StackFrameLimit
+	apperror.StackFrameLimit = 1
+	t.Cleanup(func() { apperror.StackFrameLimit = previous })
+	if got := strings.Count(err.FormatStack(), "\n"); got != 1 {
+		t.Errorf("FormatStack() with a limit of 1 rendered %d frames", got)
+	}
+
+	if got := apperror.InternalServerError(errors.New("boom")).FormatStack(); got != "" {
+		t.Errorf("FormatStack() without a stack = %q, want empty", got)
+	}
+}
+
+func TestRegisterCategoryAlias(t *testing.T) {
+	apperror.RegisterCategoryAlias("NotFoundError", apperror.ErrNotFound)
+	t.Cleanup(func() { apperror.UnregisterCategoryAlias("NotFoundError") })
+
+	got, err := apperror.ParseCategory("NotFoundError")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != apperror.ErrNotFound {
+		t.Errorf("ParseCategory(alias) = %v, want %v", got, apperror.ErrNotFound)
+	}
+	if got.String() != "NotFouncError" {
+		t.Errorf("String() = %q, want the alias not to change it", got.String())
+	}
+
+	if _, err := apperror.ParseCategory("LegacyUnknownError"); err == nil {
+		t.Error("ParseCategory of an unregistered name succeeded")
+	}
+}
+
+func TestValidationBuilder(t *testing.T) {
+	if err := apperror.NewValidationBuilder().Err(); err != nil {
+		t.Errorf("Err() without fields = %v, want nil", err)
+	}
+
+	err := apperror.NewValidationBuilder().
+		AddFieldWithCode("password", "too_short", "must be at least 8 characters").
+		AddField("name", "is required").
+		Err()
+	if err.Code.Category != apperror.ErrValidation || len(err.Errors) != 2 {
+		t.Fatalf("Err() = %v with %d sub-errors, want a validation error with 2", err, len(err.Errors))
+	}
+
+	data, marshalErr := err.ProblemJSON()
+	if marshalErr != nil {
+		t.Fatal(marshalErr)
+	}
+	var got struct {
+		Errors map[string]map[string]string `json:"errors"`
+	}
+	if unmarshalErr := json.Unmarshal(data, &got); unmarshalErr != nil {
+		t.Fatal(unmarshalErr)
+	}
+	want := map[string]map[string]string{
+		"password": {"code": "too_short", "message": "must be at least 8 characters"},
+		"name":     {"message": "is required"},
+	}
+	if !reflect.DeepEqual(got.Errors, want) {
+		t.Errorf("errors = %v, want %v", got.Errors, want)
+	}
+}
+
+func TestHTTPError(t *testing.T) {
+	rec := httptest.NewRecorder()
+	apperror.HTTPError(rec, fmt.Errorf("handler: %w", apperror.NotFound(errors.New("user not found"))))
+
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+	if got := rec.Header().Get("Content-Type"); got != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", got)
+	}
+	if want := `{"message":"user not found","status":404,"category":"not_found"}`; rec.Body.String() != want {
+		t.Errorf("body = %s, want %s", rec.Body.String(), want)
+	}
+
+	rec = httptest.NewRecorder()
+	apperror.HTTPError(rec, errors.New("database password is hunter2"))
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("plain error status = %d, want %d", rec.Code, http.StatusInternalServerError)
+	}
+	if got := rec.Header().Get("Content-Type"); got != "text/plain; charset=utf-8" {
+		t.Errorf("plain error Content-Type = %q, want text/plain", got)
+	}
+	if body := rec.Body.String(); body != "Internal Server Error\n" {
+		t.Errorf("plain error body = %q, want the generic status text", body)
+	}
+}
+
+func TestHTTPErrorHeaders(t *testing.T) {
+	rec := httptest.NewRecorder()
+	apperror.HTTPError(rec, apperror.Unauthorized(errors.New("token expired")).WithChallenge("Bearer", "api"))
+	if got := rec.Header().Get("WWW-Authenticate"); !strings.HasPrefix(got, "Bearer") {
+		t.Errorf("WWW-Authenticate = %q, want a Bearer challenge", got)
+	}
+
+	rec = httptest.NewRecorder()
+	apperror.HTTPError(rec, apperror.TooManyRequests(errors.New("slow down")).WithRetry(30*time.Second))
+	if rec.Code != http.StatusTooManyRequests {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusTooManyRequests)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "30" {
+		t.Errorf("Retry-After = %q, want 30", got)
+	}
+}
+
+func TestWrap(t *testing.T) {
+	inner := apperror.NotFound(errors.New("user 42 not found"))
+
+	err := apperror.Wrap(inner, "load profile")
+	if err.Code.Category != apperror.ErrNotFound || err.HTTPStatus() != http.StatusNotFound {
+		t.Errorf("category = %v, status = %d, want the inner 404", err.Code.Category, err.HTTPStatus())
+	}
+	if err.Error() != "load profile: user 42 not found" || err.PublicMessage() != "load profile" {
+		t.Errorf("Error() = %q, PublicMessage() = %q", err.Error(), err.PublicMessage())
+	}
+	if !errors.Is(err, inner) {
+		t.Error("errors.Is(wrapper, inner) = false, want true")
+	}
+
+	twice := apperror.Wrap(fmt.Errorf("handler: %w", err), "render page")
+	if twice.HTTPStatus() != http.StatusNotFound {
+		t.Errorf("status wrapped twice = %d, want %d", twice.HTTPStatus(), http.StatusNotFound)
+	}
+
+	overridden := apperror.Wrap(inner, "load profile",
+		apperror.WithCategory(apperror.ErrInternal), apperror.WithStatus(http.StatusInternalServerError))
+	if overridden.Code.Category != apperror.ErrInternal || overridden.HTTPStatus() != http.StatusInternalServerError {
+		t.Errorf("overridden category = %v, status = %d", overridden.Code.Category, overridden.HTTPStatus())
+	}
+
+	recategorized := apperror.Wrap(inner, "x", apperror.WithCategory(apperror.ErrInternal))
+	if recategorized.HTTPStatus() != http.StatusInternalServerError {
+		t.Errorf("recategorized status = %d, want %d", recategorized.HTTPStatus(), http.StatusInternalServerError)
+	}
+	if kept := apperror.Wrap(inner, "x", apperror.WithCategory(apperror.ErrInternal), apperror.WithStatus(http.StatusNotFound)); kept.HTTPStatus() != http.StatusNotFound {
+		t.Errorf("explicit status = %d, want %d", kept.HTTPStatus(), http.StatusNotFound)
+	}
+
+	if plain := apperror.Wrap(os.ErrNotExist, "open config"); plain.Code.Category != apperror.ErrNotFound {
+		t.Errorf("plain error category = %v, want %v", plain.Code.Category, apperror.ErrNotFound)
+	}
+	if apperror.Wrap(nil, "nothing") != nil {
+		t.Error("Wrap(nil) != nil")
+	}
+}
+
+func TestBinaryRoundTrip(t *testing.T) {
+	sub := apperror.BadRequest(errors.New("name is required")).WithField("name")
+	original := apperror.NewMultiError(apperror.ErrValidation, sub).
+		WithRow(3).
+		WithRequestID("req-1").
+		WithService("users").
+		WithSeverity(apperror.SeverityCritical).
+		WithStatusText("Invalid Input").
+		WithOp("users.Create").
+		WithRetry(time.Second).
+		WithStack()
+	original.Code.Internal = 1001
+
+	data, err := original.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got apperror.AppError
+	if err := got.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+
+	if got.Code != original.Code || got.Status != original.Status || got.StatusText != original.StatusText ||
+		got.Message != original.Message || got.Severity != original.Severity || !got.Retryable {
+		t.Errorf("decoded %+v, want %+v", got, *original)
+	}
+	if !maps.Equal(got.Metadata, original.Metadata) || !slices.Equal(got.Ops, original.Ops) {
+		t.Errorf("decoded metadata %v and ops %v, want %v and %v", got.Metadata, got.Ops, original.Metadata, original.Ops)
+	}
+	if got.Error() != original.Error() {
+		t.Errorf("decoded Error() = %q, want %q", got.Error(), original.Error())
+	}
+	if len(got.Errors) != 1 || !got.Errors[0].EqualIgnoringCause(sub) {
+		t.Errorf("decoded sub-errors = %v, want [%v]", got.Errors, sub)
+	}
+	if got.Stack != nil {
+		t.Error("the stack should not be encoded")
+	}
+
+	for name, data := range map[string][]byte{
+		"unknown version": append([]byte{99}, data[1:]...),
+		"truncated":       data[:len(data)/2],
+		"empty":           nil,
+	} {
+		if err := new(apperror.AppError).UnmarshalBinary(data); err == nil {
+			t.Errorf("%s: UnmarshalBinary() succeeded, want an error", name)
+		}
+	}
+	if err := new(apperror.AppError).UnmarshalBinary([]byte{99}); err == nil || !strings.Contains(err.Error(), "version 99") {
+		t.Errorf("unknown version error = %v", err)
+	}
+}
+
+func TestDefaultInternalCode(t *testing.T) {
+	apperror.DefaultInternalCode[apperror.ErrValidation] = 1000
+	t.Cleanup(func() { delete(apperror.DefaultInternalCode, apperror.ErrValidation) })
+
+	cause := errors.New("bad")
+	if got := apperror.NewAppError(cause, apperror.ErrValidation, nil).Code.Internal; got != 1000 {
+		t.Errorf("configured category Code.Internal = %d, want 1000", got)
+	}
+	if got := apperror.NewAppError(cause, apperror.ErrValidation, intPtr(1042)).Code.Internal; got != 1042 {
+		t.Errorf("explicit code Code.Internal = %d, want 1042", got)
+	}
+	if got := apperror.NewAppError(cause, apperror.ErrInternal, nil).Code.Internal; got != 0 {
+		t.Errorf("unconfigured category Code.Internal = %d, want 0", got)
+	}
+}
+
+func TestMetadataAccessors(t *testing.T) {
+	err := apperror.BadRequest(errors.New("bad")).
+		WithField("email").
+		WithRow(7).
+		WithInfo("must be unique").
+		WithRequestID("req-1")
+
+	if got := err.Field(); got != "email" {
+		t.Errorf("Field() = %q, want email", got)
+	}
+	if row, ok := err.Row(); !ok || row != 7 {
+		t.Errorf("Row() = %d, %v, want 7, true", row, ok)
+	}
+	if got := err.Info(); got != "must be unique" {
+		t.Errorf("Info() = %q, want must be unique", got)
+	}
+	if got := err.RequestID(); got != "req-1" {
+		t.Errorf("RequestID() = %q, want req-1", got)
+	}
+
+	absent := apperror.BadRequest(errors.New("bad"))
+	if absent.Field() != "" || absent.Info() != "" || absent.RequestID() != "" {
+		t.Error("accessors of absent metadata should return the empty string")
+	}
+	if row, ok := absent.Row(); ok || row != 0 {
+		t.Errorf("absent Row() = %d, %v, want 0, false", row, ok)
+	}
+
+	malformed := absent.WithMetadata("row", "seven")
+	if row, ok := malformed.Row(); ok || row != 0 {
+		t.Errorf("malformed Row() = %d, %v, want 0, false", row, ok)
+	}
+}
+
+func TestFilterBySeverity(t *testing.T) {
+	warning := apperror.NotFound(errors.New("missing"))
+	failure := apperror.InternalServerError(errors.New("boom"))
+	critical := apperror.BadRequest(errors.New("tampered")).WithSeverity(apperror.SeverityCritical)
+	errs := []error{warning, errors.New("plain"), fmt.Errorf("wrapped: %w", failure), critical, nil}
+
+	tests := []struct {
+		min  apperror.Severity
+		want []*apperror.AppError
+	}{
+		{apperror.SeverityInfo, []*apperror.AppError{warning, failure, critical}},
+		{apperror.SeverityError, []*apperror.AppError{failure, critical}},
+		{apperror.SeverityCritical, []*apperror.AppError{critical}},
+	}
+	for _, tt := range tests {
+		if got := apperror.FilterBySeverity(errs, tt.min); !slices.Equal(got, tt.want) {
+			t.Errorf("FilterBySeverity(%v) = %v, want %v", tt.min, got, tt.want)
+		}
+	}
+}
+
+func TestCLIString(t *testing.T) {
+	for _, c := range apperror.AllCategories() {
+		if c.Symbol() == "" {
+			t.Errorf("%v.Symbol() is empty", c)
+		}
+	}
+	if got := apperror.ErrInternal.Symbol(); got != "✗" {
+		t.Errorf("ErrInternal.Symbol() = %q, want ✗", got)
+	}
+	if got := apperror.ErrValidation.Symbol(); got != "!" {
+		t.Errorf("ErrValidation.Symbol() = %q, want !", got)
+	}
+
+	err := apperror.InternalServerError(errors.New("database unavailable"))
+
+	colored := err.CLIString()
+	if !strings.HasPrefix(colored, "\x1b[31m✗ InternalError\x1b[0m") || !strings.HasSuffix(colored, ": database unavailable") {
+		t.Errorf("colored CLIString() = %q", colored)
+	}
+
+	apperror.CLIColor = false
+	t.Cleanup(func() { apperror.CLIColor = true })
+	if got, want := err.CLIString(), "✗ InternalError: database unavailable"; got != want {
+		t.Errorf("CLIString() without color = %q, want %q", got, want)
+	}
+}
+
+func TestWithFieldIndependentCopies(t *testing.T) {
+	base := apperror.BadRequest(errors.New("bad")).WithInfo("shared")
+
+	a := base.WithField("a")
+	b := base.WithField("b")
+
+	if got := a.Metadata["field"]; got != "a" {
+		t.Errorf(`a.Metadata["field"] = %q, want "a"`, got)
+	}
+	if got := b.Metadata["field"]; got != "b" {
+		t.Errorf(`b.Metadata["field"] = %q, want "b"`, got)
+	}
+	if _, ok := base.Metadata["field"]; ok {
+		t.Errorf("base metadata = %v, want no field", base.Metadata)
+	}
+}
+
+func TestFlatten(t *testing.T) {
+	root := errors.New("no rows")
+	repo := apperror.NotFound(root).WithOp("repo.GetUser").WithResource("user").WithInfo("repo")
+	service := apperror.NewAppError(fmt.Errorf("lookup: %w", repo), apperror.ErrNotFound, nil).
+		WithOp("service.GetUser").WithInfo("service").WithRequestID("req-1")
+	handler := apperror.BadRequest(service).WithOp("handler.Get").WithInfo("handler")
+	handler.Message = "user not found"
+
+	flat := apperror.Flatten(fmt.Errorf("http: %w", handler))
+
+	if flat.Code.Category != apperror.ErrValidation || flat.Status != http.StatusBadRequest {
+		t.Errorf("category = %v, status = %d, want the outermost", flat.Code.Category, flat.Status)
+	}
+	if flat.PublicMessage() != "user not found" {
+		t.Errorf("PublicMessage() = %q, want the outermost message", flat.PublicMessage())
+	}
+	if want := []string{"handler.Get", "service.GetUser", "repo.GetUser"}; !slices.Equal(flat.Ops, want) {
+		t.Errorf("Ops = %v, want %v", flat.Ops, want)
+	}
+	if want := "handler.Get -> service.GetUser -> repo.GetUser: no rows"; flat.Error() != want {
+		t.Errorf("Error() = %q, want %q", flat.Error(), want)
+	}
+	want := map[string]string{"info": "repo", "resource": "user", "request_id": "req-1"}
+	if !maps.Equal(flat.Metadata, want) {
+		t.Errorf("Metadata = %v, want %v", flat.Metadata, want)
+	}
+	if !errors.Is(flat, root) {
+		t.Error("errors.Is(flat, root) = false, want true")
+	}
+
+	if got := apperror.Flatten(os.ErrNotExist); got.Code.Category != apperror.ErrNotFound {
+		t.Errorf("Flatten(plain) category = %v, want %v", got.Code.Category, apperror.ErrNotFound)
+	}
+	if apperror.Flatten(nil) != nil {
+		t.Error("Flatten(nil) != nil")
+	}
+}
+
+func TestJSONAPIError(t *testing.T) {
+	single := apperror.NotFound(errors.New("user not found")).WithResource("user").WithService("users")
+	single.Code.Internal = 42
+
+	want := map[string]any{
+		"status": "404",
+		"code":   "users/42",
+		"title":  "Not Found",
+		"detail": "user not found",
+		"meta":   map[string]string{"resource": "user"},
+	}
+	if got := single.JSONAPIError(); !reflect.DeepEqual(got, want) {
+		t.Errorf("JSONAPIError() = %v, want %v", got, want)
+	}
+
+	multi := apperror.NewMultiError(apperror.ErrValidation,
+		apperror.BadRequest(errors.New("name is required")),
+		apperror.BadRequest(errors.New("email is invalid")).WithField("email"),
+	)
+	data, err := json.Marshal(multi.JSONAPIError())
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantJSON := `{"errors":[` +
+		`{"detail":"name is required","status":"400","title":"Bad Request"},` +
+		`{"detail":"email is invalid","meta":{"field":"email"},"status":"400","title":"Bad Request"}]}`
+	if string(data) != wantJSON {
+		t.Errorf("multi-error JSONAPIError() = %s, want %s", data, wantJSON)
+	}
+}
+
+func TestJSONAPIErrorProduction(t *testing.T) {
+	leaky := errors.New("dial tcp 10.0.0.5:5432: password auth failed")
+
+	obj := apperror.InternalServerError(leaky).JSONAPIError()
+	if detail := obj["detail"]; detail != "Internal Server Error" {
+		t.Errorf("detail = %q, want the generic status text", detail)
+	}
+	data, err := json.Marshal(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "password") {
+		t.Errorf("JSONAPIError() = %s, leaks the wrapped error", data)
+	}
+}
+
+func TestUnavailable(t *testing.T) {
+	err := apperror.Unavailable(errors.New("database is down"))
+	if err.HTTPStatus() != http.StatusServiceUnavailable || err.Code.Category != apperror.ErrUnavailable {
+		t.Errorf("status = %d, category = %v, want 503 unavailable", err.HTTPStatus(), err.Code.Category)
+	}
+	if !err.Retryable {
+		t.Error("Unavailable() is not Retryable by default")
+	}
+	if apperror.InternalServerError(errors.New("boom")).Retryable {
+		t.Error("InternalServerError() is Retryable")
+	}
+	if got := apperror.CategoryForStatus(http.StatusServiceUnavailable); got != apperror.ErrUnavailable {
+		t.Errorf("CategoryForStatus(503) = %v, want %v", got, apperror.ErrUnavailable)
+	}
+
+	retry := apperror.BadRequest(errors.New("busy")).WithRetry(30 * time.Second)
+	if !retry.Retryable || retry.Metadata["retry_after"] != "30" {
+		t.Errorf("WithRetry() = Retryable %v, metadata %v", retry.Retryable, retry.Metadata)
+	}
+}
+
+func TestWithSampledStack(t *testing.T) {
+	previousRate, previousRandom := apperror.StackSampleRate, apperror.SampleRandom
+	apperror.StackSampleRate = 10
+	apperror.ResetStackSamples()
+	t.Cleanup(func() {
+		apperror.StackSampleRate = previousRate
+		apperror.SampleRandom = previousRandom
+		apperror.ResetStackSamples()
+	})
+
+	random := 0.5
+	apperror.SampleRandom = func() float64 { return random }
+
+	base := apperror.InternalServerError(errors.New("sampled stack " + t.Name()))
+	if first := base.WithSampledStack(); len(first.Stack) == 0 {
+		t.Error("the first occurrence has no stack")
+	}
+	for range 3 {
+		if next := base.WithSampledStack(); len(next.Stack) != 0 {
+			t.Error("a sampled-out occurrence has a stack")
+		}
+	}
+
+	random = 0.05
+	if sampled := base.WithSampledStack(); len(sampled.Stack) == 0 {
+		t.Error("a sampled-in occurrence has no stack")
+	}
+
+	random = 0.5
+	other := apperror.InternalServerError(errors.New("other sampled stack " + t.Name()))
+	if first := other.WithSampledStack(); len(first.Stack) == 0 {
+		t.Error("the first occurrence of another error has no stack")
+	}
+	if notFound := apperror.NotFound(errors.New("missing")); len(notFound.WithSampledStack().Stack) == 0 {
+		t.Error("a non-internal error has no stack")
+	}
+}
+
+func TestCollector(t *testing.T) {
+	var c apperror.Collector
+	for i := range 8 {
+		c.Go(func() error {
+			switch i % 3 {
+			case 0:
+				return apperror.NotFound(fmt.Errorf("item %d not found", i))
+			case 1:
+				return fmt.Errorf("item %d failed", i)
+			default:
+				return nil
+			}
+		})
+	}
+
+	if c.Wait() == nil {
+		t.Fatal("Wait() = nil, want a multi-error")
+	}
+	err := c.AppError()
+	if len(err.Errors) != 6 {
+		t.Errorf("collected %d errors, want 6: %v", len(err.Errors), err)
+	}
+	if err.Code.Category != apperror.ErrInternal {
+		t.Errorf("category = %v, want %v", err.Code.Category, apperror.ErrInternal)
+	}
+
+	var empty apperror.Collector
+	empty.Go(func() error { return nil })
+	if err := empty.Wait(); err != nil {
+		t.Errorf("Wait() without failures = %v, want nil", err)
+	}
+	if err := empty.AppError(); err != nil {
+		t.Errorf("AppError() without failures = %v, want nil", err)
+	}
+}
+
+func TestMarshalJSONProductionMessage(t *testing.T) {
+	leaky := errors.New("dial tcp 10.0.0.5:5432: password auth failed")
+
+	tests := []struct {
+		name string
+		err  *apperror.AppError
+		want string
+	}{
+		{"NewAppError", apperror.NewAppError(leaky, apperror.ErrInternal, nil), `{"message":"Internal Server Error","category":"server_error"}`},
+		{"InternalServerError", apperror.InternalServerError(leaky), `{"message":"Internal Server Error","status":500,"category":"server_error"}`},
+		{"Unavailable", apperror.Unavailable(leaky), `{"message":"Service Unavailable","status":503,"category":"unavailable"}`},
+		{"client error", apperror.NewAppError(errors.New("name is required"), apperror.ErrValidation, nil), `{"message":"name is required","category":"invalid_request"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := json.Marshal(tt.err)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != tt.want {
+				t.Errorf("MarshalJSON() = %s, want %s", data, tt.want)
+			}
+		})
+	}
+
+	apperror.Mode = apperror.ModeDevelopment
+	t.Cleanup(func() { apperror.Mode = apperror.ModeProduction })
+	if got := apperror.InternalServerError(leaky).SafeMessage(); got != leaky.Error() {
+		t.Errorf("development SafeMessage() = %q, want the wrapped error message", got)
+	}
+}
+
+func TestProblemJSONProduction(t *testing.T) {
+	err := apperror.NewAppError(errors.New("token replay for user 7"), apperror.ErrSecurity, nil)
+
+	data, marshalErr := err.ProblemJSON()
+	if marshalErr != nil {
+		t.Fatal(marshalErr)
+	}
+	want := `{"type":"/errors/server_error","title":"Internal Server Error","status":500,"detail":"Internal Server Error"}`
+	if string(data) != want {
+		t.Errorf("ProblemJSON() = %s, want %s", data, want)
+	}
+
+	apperror.Mode = apperror.ModeDevelopment
+	t.Cleanup(func() { apperror.Mode = apperror.ModeProduction })
+	data, marshalErr = err.ProblemJSON()
+	if marshalErr != nil {
+		t.Fatal(marshalErr)
+	}
+	if !strings.Contains(string(data), `"type":"/errors/SecurityError"`) || !strings.Contains(string(data), "token replay") {
+		t.Errorf("development ProblemJSON() = %s, want the internal details", data)
+	}
+}
@@ -0,0 +1,42 @@
+// Package apperrortest provides factories of representative AppErrors for tests of
+// middleware and renderers.
+package apperrortest
+
+import (
+	"errors"
+
+	"github.com/ckminhano/golib/apperror"
+)
+
+// RequestID is the request id set on every error built by this package.
+const RequestID = "apperrortest-request"
+
+// Validation returns an ErrValidation error for the field, with the "field", "info"
+// and "request_id" metadata set.
+func Validation(field string) *apperror.AppError {
+	return apperror.BadRequest(errors.New(field + " is invalid")).
+		WithField(field).
+		WithInfo("the value of " + field + " is invalid").
+		WithRequestID(RequestID).
+		WithOp("apperrortest.Validation")
+}
+
+// NotFound returns an ErrNotFound error for the resource, with the "resource" and
+// "request_id" metadata set.
+func NotFound(resource string) *apperror.AppError {
+	return apperror.NotFound(errors.New(resource + " not found")).
+		WithResource(resource).
+		WithRequestID(RequestID).
+		WithOp("apperrortest.NotFound")
+}
+
+// Internal returns an ErrInternal error with a generic public message, a cause that
+// must not reach clients, the "request_id" metadata and a captured stack.
+func Internal() *apperror.AppError {
+	err := apperror.InternalServerError(errors.New("apperrortest: connection refused")).
+		WithRequestID(RequestID).
+		WithOp("apperrortest.Internal").
+		WithStack()
+	err.Message = "Internal Server Error"
+	return err
+}
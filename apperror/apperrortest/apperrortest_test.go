@@ -0,0 +1,55 @@
+package apperrortest_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/ckminhano/golib/apperror"
+	"github.com/ckminhano/golib/apperror/apperrortest"
+)
+
+func TestFactories(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      *apperror.AppError
+		category apperror.Category
+		status   int
+		metadata map[string]string
+	}{
+		{
+			"Validation", apperrortest.Validation("email"), apperror.ErrValidation, http.StatusBadRequest,
+			map[string]string{"field": "email", "request_id": apperrortest.RequestID},
+		},
+		{
+			"NotFound", apperrortest.NotFound("user"), apperror.ErrNotFound, http.StatusNotFound,
+			map[string]string{"resource": "user", "request_id": apperrortest.RequestID},
+		},
+		{
+			"Internal", apperrortest.Internal(), apperror.ErrInternal, http.StatusInternalServerError,
+			map[string]string{"request_id": apperrortest.RequestID},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.err.Code.Category != tt.category {
+				t.Errorf("Code.Category = %v, want %v", tt.err.Code.Category, tt.category)
+			}
+			if tt.err.Status != tt.status {
+				t.Errorf("Status = %d, want %d", tt.err.Status, tt.status)
+			}
+			for k, v := range tt.metadata {
+				if got := tt.err.Metadata[k]; got != v {
+					t.Errorf("Metadata[%q] = %q, want %q", k, got, v)
+				}
+			}
+			if len(tt.err.Ops) == 0 || tt.err.Message == "" {
+				t.Errorf("error %+v is not fully populated", tt.err)
+			}
+		})
+	}
+
+	if len(apperrortest.Internal().Stack) == 0 {
+		t.Error("Internal() did not capture a stack")
+	}
+}
@@ -0,0 +1,189 @@
+package apperror
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+)
+
+// binaryVersion is the version byte written first by MarshalBinary.
+// Version 2 added the Retryable field; UnmarshalBinary still reads version 1.
+const binaryVersion = 2
+
+// MarshalBinary implements the encoding.BinaryMarshaler interface with a compact, versioned
+// encoding, e.g. to cache errors as bytes. It keeps the category, status, status text,
+// code, message, severity, retryability, metadata, operations, the message of the wrapped error and
+// the sub-errors. Details and the captured stack are not encoded.
+func (err AppError) MarshalBinary() ([]byte, error) {
+	return err.appendBinary([]byte{binaryVersion}), nil
+}
+
+func (err AppError) appendBinary(b []byte) []byte {
+	b = binary.AppendUvarint(b, uint64(err.Code.Category))
+	b = binary.AppendVarint(b, int64(err.Status))
+	b = appendString(b, err.StatusText)
+	b = binary.AppendVarint(b, int64(err.Code.Internal))
+	b = appendString(b, err.Code.Service)
+	b = appendString(b, err.Message)
+	b = binary.AppendVarint(b, int64(err.Severity))
+	b = binary.AppendUvarint(b, boolToUvarint(err.Retryable))
+
+	var cause string
+	if err.Err != nil {
+		cause = err.Err.Error()
+	}
+	b = appendString(b, cause)
+
+	b = binary.AppendUvarint(b, uint64(len(err.Metadata)))
+	for _, k := range slices.Sorted(maps.Keys(err.Metadata)) {
+		b = appendString(b, k)
+		b = appendString(b, err.Metadata[k])
+	}
+
+	b = binary.AppendUvarint(b, uint64(len(err.Ops)))
+	for _, op := range err.Ops {
+		b = appendString(b, op)
+	}
+
+	b = binary.AppendUvarint(b, uint64(len(err.Errors)))
+	for _, sub := range err.Errors {
+		b = sub.appendBinary(b)
+	}
+	return b
+}
+
+func boolToUvarint(v bool) uint64 {
+	if v {
+		return 1
+	}
+	return 0
+}
+
+func appendString(b []byte, s string) []byte {
+	b = binary.AppendUvarint(b, uint64(len(s)))
+	return append(b, s...)
+}
+
+// UnmarshalBinary implements the encoding.BinaryUnmarshaler interface for data written by
+// MarshalBinary. The wrapped error is restored as a new error holding its message.
+// It returns an error for an unknown version or truncated data.
+func (err *AppError) UnmarshalBinary(data []byte) error {
+	if len(data) == 0 {
+		return errors.New("apperror: empty binary encoding")
+	}
+	version := data[0]
+	if version < 1 || version > binaryVersion {
+		return fmt.Errorf("apperror: unknown binary encoding version %d", version)
+	}
+
+	r := bytes.NewReader(data[1:])
+	decoded, decodeErr := readBinary(r, version)
+	if decodeErr != nil {
+		return fmt.Errorf("apperror: invalid binary encoding: %w", decodeErr)
+	}
+	if r.Len() != 0 {
+		return errors.New("apperror: invalid binary encoding: trailing data")
+	}
+	*err = *decoded
+	return nil
+}
+
+// binaryReader decodes the fields of a binary encoding, keeping the first error.
+type binaryReader struct {
+	r   *bytes.Reader
+	err error
+}
+
+func (br *binaryReader) uvarint() uint64 {
+	if br.err != nil {
+		return 0
+	}
+	v, err := binary.ReadUvarint(br.r)
+	br.err = err
+	return v
+}
+
+func (br *binaryReader) varint() int64 {
+	if br.err != nil {
+		return 0
+	}
+	v, err := binary.ReadVarint(br.r)
+	br.err = err
+	return v
+}
+
+func (br *binaryReader) string() string {
+	n := br.uvarint()
+	if br.err != nil {
+		return ""
+	}
+	if n > uint64(br.r.Len()) {
+		br.err = io.ErrUnexpectedEOF
+		return ""
+	}
+	b := make([]byte, n)
+	_, br.err = io.ReadFull(br.r, b)
+	return string(b)
+}
+
+// count reads a length prefix, rejecting lengths that cannot fit in the remaining data.
+func (br *binaryReader) count() int {
+	n := br.uvarint()
+	if br.err == nil && n > uint64(br.r.Len()) {
+		br.err = io.ErrUnexpectedEOF
+	}
+	if br.err != nil {
+		return 0
+	}
+	return int(n)
+}
+
+func readBinary(r *bytes.Reader, version byte) (*AppError, error) {
+	br := &binaryReader{r: r}
+	err := &AppError{}
+	err.Code.Category = Category(br.uvarint())
+	err.Status = int(br.varint())
+	err.StatusText = br.string()
+	err.Code.Internal = ErrorCode(br.varint())
+	err.Code.Service = br.string()
+	err.Message = br.string()
+	err.Severity = Severity(br.varint())
+	if version >= 2 {
+		err.Retryable = br.uvarint() == 1
+	}
+	if cause := br.string(); cause != "" {
+		err.Err = errors.New(cause)
+	}
+
+	if n := br.count(); n > 0 {
+		err.Metadata = make(map[string]string, n)
+		for range n {
+			k := br.string()
+			err.Metadata[k] = br.string()
+		}
+	}
+
+	for range br.count() {
+		err.Ops = append(err.Ops, br.string())
+	}
+
+	for range br.count() {
+		if br.err != nil {
+			break
+		}
+		sub, subErr := readBinary(r, version)
+		if subErr != nil {
+			return nil, subErr
+		}
+		err.Errors = append(err.Errors, sub)
+	}
+
+	if br.err != nil {
+		return nil, br.err
+	}
+	return err, nil
+}
@@ -0,0 +1,46 @@
+package apperror
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+)
+
+var (
+	budgetMu     sync.RWMutex
+	budgetPolicy = defaultErrorBudgetPolicy
+)
+
+// defaultErrorBudgetPolicy excludes client errors (4xx) from the error budget.
+func defaultErrorBudgetPolicy(err *AppError) bool {
+	status := err.HTTPStatus()
+	return status >= http.StatusBadRequest && status < http.StatusInternalServerError
+}
+
+// SetErrorBudgetPolicy sets the predicate used by ExcludeFromErrorBudget, which returns
+// true for AppErrors that must not count against the error budget. A nil policy
+// restores the default, which excludes client errors (4xx). It is safe for concurrent use.
+func SetErrorBudgetPolicy(policy func(*AppError) bool) {
+	if policy == nil {
+		policy = defaultErrorBudgetPolicy
+	}
+
+	budgetMu.Lock()
+	defer budgetMu.Unlock()
+	budgetPolicy = policy
+}
+
+// ExcludeFromErrorBudget reports whether err must not count against the error budget,
+// according to the policy set with SetErrorBudgetPolicy. Errors that are not an
+// AppError always count against the budget.
+func ExcludeFromErrorBudget(err error) bool {
+	var appErr *AppError
+	if !errors.As(err, &appErr) {
+		return false
+	}
+
+	budgetMu.RLock()
+	policy := budgetPolicy
+	budgetMu.RUnlock()
+	return policy(appErr)
+}
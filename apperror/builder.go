@@ -0,0 +1,37 @@
+package apperror
+
+import "errors"
+
+// ValidationBuilder collects field errors of a request, e.g. a submitted form, into a
+// single ErrValidation multi-error. The zero value is ready to use.
+type ValidationBuilder struct {
+	errs []*AppError
+}
+
+// NewValidationBuilder creates an empty ValidationBuilder.
+func NewValidationBuilder() *ValidationBuilder {
+	return &ValidationBuilder{}
+}
+
+// AddField adds an error for the field.
+func (b *ValidationBuilder) AddField(field, message string) *ValidationBuilder {
+	b.errs = append(b.errs, NewAppError(errors.New(message), ErrValidation, nil).WithField(field))
+	return b
+}
+
+// AddFieldWithCode adds an error for the field with a machine-readable code, e.g. "too_short",
+// stored in the "field_code" metadata of the sub-error.
+func (b *ValidationBuilder) AddFieldWithCode(field, code, message string) *ValidationBuilder {
+	b.errs = append(b.errs, NewAppError(errors.New(message), ErrValidation, nil).
+		WithField(field).
+		withMetadata("field_code", code))
+	return b
+}
+
+// Err returns the collected field errors as an ErrValidation multi-error, or nil if none was added.
+func (b *ValidationBuilder) Err() *AppError {
+	if len(b.errs) == 0 {
+		return nil
+	}
+	return NewMultiError(ErrValidation, b.errs...)
+}
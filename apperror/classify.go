@@ -0,0 +1,40 @@
+package apperror
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+)
+
+// Classify returns an AppError categorized from well-known standard library errors.
+// If err already is an AppError, it is returned as is. Unrecognized errors,
+// including io.EOF, are classified as ErrInternal. It returns nil if err is nil.
+func Classify(err error) *AppError {
+	if err == nil {
+		return nil
+	}
+
+	var appErr *AppError
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+
+	category := ErrInternal
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		category = ErrNotFound
+	case errors.Is(err, os.ErrPermission):
+		category = ErrForbidden
+	case errors.Is(err, context.Canceled):
+		category = ErrCanceled
+	case IsTimeout(err):
+		category = ErrTimeout
+	case errors.Is(err, io.EOF):
+		category = ErrInternal
+	}
+
+	appErr = NewAppError(err, category, nil)
+	appErr.Status = category.HTTPStatus()
+	return appErr
+}
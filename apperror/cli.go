@@ -0,0 +1,63 @@
+package apperror
+
+// CLIColor enables ANSI colors in CLIString. Disable it when the output is not a terminal.
+var CLIColor = true
+
+const (
+	ansiRed    = "\x1b[31m"
+	ansiYellow = "\x1b[33m"
+	ansiBlue   = "\x1b[34m"
+	ansiReset  = "\x1b[0m"
+)
+
+// Symbol returns a short marker of the category for terminal output, e.g. "✗" for
+// ErrInternal and "!" for ErrValidation.
+func (c Category) Symbol() string {
+	switch c {
+	case ErrValidation:
+		return "!"
+	case ErrInternal:
+		return "✗"
+	case ErrNotFound:
+		return "?"
+	case ErrMethoNotAllowed, ErrForbidden, ErrUnauthorized:
+		return "⊘"
+	case ErrSecurity:
+		return "⚠"
+	case ErrTimeout:
+		return "⏱"
+	case ErrCanceled:
+		return "-"
+	case ErrTooManyRequests:
+		return "…"
+	case ErrConflict:
+		return "≠"
+	case ErrUnavailable:
+		return "↻"
+	default:
+		return "•"
+	}
+}
+
+// CLIString renders the AppError for terminal output as the category symbol, the category
+// and the public message, e.g. "✗ InternalError: database unavailable". When CLIColor is
+// set, the symbol and category are colored by severity.
+func (err AppError) CLIString() string {
+	category := err.Code.Category
+	prefix := category.Symbol() + " " + category.String()
+	if CLIColor {
+		prefix = severityColor(err.EffectiveSeverity()) + prefix + ansiReset
+	}
+	return prefix + ": " + err.PublicMessage()
+}
+
+func severityColor(s Severity) string {
+	switch {
+	case s >= SeverityError:
+		return ansiRed
+	case s == SeverityWarning:
+		return ansiYellow
+	default:
+		return ansiBlue
+	}
+}
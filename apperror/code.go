@@ -0,0 +1,50 @@
+package apperror
+
+import (
+	"strconv"
+	"sync"
+)
+
+// ErrorCode is an internal application error code.
+// It is an int, so untyped integer constants can be used wherever an ErrorCode is expected.
+type ErrorCode int
+
+var (
+	codeNamesMu sync.RWMutex
+	codeNames   = map[ErrorCode]string{}
+)
+
+// RegisterCode registers a human-readable name for the error code, e.g. "user_not_found".
+// It is safe for concurrent use. Registering a code again replaces its name.
+func RegisterCode(code ErrorCode, name string) {
+	codeNamesMu.Lock()
+	defer codeNamesMu.Unlock()
+	codeNames[code] = name
+}
+
+// unregisterCode removes the name of the error code, so tests can undo RegisterCode.
+func unregisterCode(code ErrorCode) {
+	codeNamesMu.Lock()
+	defer codeNamesMu.Unlock()
+	delete(codeNames, code)
+}
+
+// Name returns the name registered for the error code, or the empty string if there is none.
+func (c ErrorCode) Name() string {
+	codeNamesMu.RLock()
+	defer codeNamesMu.RUnlock()
+	return codeNames[c]
+}
+
+// String returns the registered name of the error code, falling back to its number.
+func (c ErrorCode) String() string {
+	if name := c.Name(); name != "" {
+		return name
+	}
+	return strconv.Itoa(int(c))
+}
+
+// Name returns the name registered for the internal code, or the empty string if there is none.
+func (c Code) Name() string {
+	return c.Internal.Name()
+}
@@ -0,0 +1,46 @@
+package apperror
+
+import "sync"
+
+// Collector runs functions concurrently and collects every error they return, unlike
+// errgroup.Group which only keeps the first one. The zero value is ready to use.
+// A Collector must not be copied after first use.
+type Collector struct {
+	wg   sync.WaitGroup
+	mu   sync.Mutex
+	errs []error
+}
+
+// Go runs f in a new goroutine and records the error it returns, if any.
+func (c *Collector) Go(f func() error) {
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		if err := f(); err != nil {
+			c.mu.Lock()
+			c.errs = append(c.errs, err)
+			c.mu.Unlock()
+		}
+	}()
+}
+
+// Wait blocks until every function started with Go has returned, then returns the
+// collected errors as a multi-error built with Join, or nil if none failed. Like
+// errgroup.Group.Wait it returns an error, so a nil result compares equal to nil;
+// use AppError to get the multi-error itself.
+func (c *Collector) Wait() error {
+	if err := c.AppError(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// AppError blocks like Wait and returns the collected errors as a multi-error, or nil
+// if none failed.
+func (c *Collector) AppError() *AppError {
+	c.wg.Wait()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return Join(c.errs...)
+}
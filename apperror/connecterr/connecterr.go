@@ -0,0 +1,79 @@
+// Package connecterr converts errors into connect-go errors, keeping the connectrpc
+// dependency out of the apperror package.
+package connecterr
+
+import (
+	"errors"
+	"net/http"
+
+	"connectrpc.com/connect"
+	"github.com/ckminhano/golib/apperror"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// Code returns the connect code matching the category.
+func Code(c apperror.Category) connect.Code {
+	switch c {
+	case apperror.ErrValidation:
+		return connect.CodeInvalidArgument
+	case apperror.ErrNotFound:
+		return connect.CodeNotFound
+	case apperror.ErrMethoNotAllowed:
+		return connect.CodeUnimplemented
+	case apperror.ErrSecurity, apperror.ErrForbidden:
+		return connect.CodePermissionDenied
+	case apperror.ErrUnauthorized:
+		return connect.CodeUnauthenticated
+	case apperror.ErrTimeout:
+		return connect.CodeDeadlineExceeded
+	case apperror.ErrCanceled:
+		return connect.CodeCanceled
+	case apperror.ErrTooManyRequests:
+		return connect.CodeResourceExhausted
+	case apperror.ErrConflict:
+		return connect.CodeAborted
+	case apperror.ErrUnavailable:
+		return connect.CodeUnavailable
+	default:
+		return connect.CodeInternal
+	}
+}
+
+// ToConnectError converts err into a connect error whose code matches its category and
+// whose message is its SafeMessage, so the cause of a 5xx error is not exposed in
+// production. The metadata of an AppError is attached as a
+// google.protobuf.Struct error detail. Errors that are not an AppError are classified
+// with apperror.Classify and get a generic message so their text is not exposed to
+// clients. It returns nil if err is nil.
+func ToConnectError(err error) *connect.Error {
+	if err == nil {
+		return nil
+	}
+
+	var appErr *apperror.AppError
+	if !errors.As(err, &appErr) {
+		classified := apperror.Classify(err)
+		message := http.StatusText(classified.HTTPStatus())
+		return connect.NewError(Code(classified.Code.Category), errors.New(message))
+	}
+
+	connectErr := connect.NewError(Code(appErr.Code.Category), errors.New(appErr.SafeMessage()))
+	if len(appErr.Metadata) > 0 {
+		if detail, detailErr := metadataDetail(appErr.Metadata); detailErr == nil {
+			connectErr.AddDetail(detail)
+		}
+	}
+	return connectErr
+}
+
+func metadataDetail(metadata map[string]string) (*connect.ErrorDetail, error) {
+	fields := make(map[string]any, len(metadata))
+	for k, v := range metadata {
+		fields[k] = v
+	}
+	s, err := structpb.NewStruct(fields)
+	if err != nil {
+		return nil, err
+	}
+	return connect.NewErrorDetail(s)
+}
@@ -0,0 +1,86 @@
+package connecterr_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"connectrpc.com/connect"
+	"github.com/ckminhano/golib/apperror"
+	"github.com/ckminhano/golib/apperror/connecterr"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestToConnectError(t *testing.T) {
+	tests := []struct {
+		category apperror.Category
+		want     connect.Code
+	}{
+		{apperror.ErrValidation, connect.CodeInvalidArgument},
+		{apperror.ErrInternal, connect.CodeInternal},
+		{apperror.ErrNotFound, connect.CodeNotFound},
+		{apperror.ErrMethoNotAllowed, connect.CodeUnimplemented},
+		{apperror.ErrSecurity, connect.CodePermissionDenied},
+		{apperror.ErrForbidden, connect.CodePermissionDenied},
+		{apperror.ErrUnauthorized, connect.CodeUnauthenticated},
+		{apperror.ErrTimeout, connect.CodeDeadlineExceeded},
+		{apperror.ErrCanceled, connect.CodeCanceled},
+		{apperror.ErrTooManyRequests, connect.CodeResourceExhausted},
+		{apperror.ErrConflict, connect.CodeAborted},
+		{apperror.ErrUnavailable, connect.CodeUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.category.String(), func(t *testing.T) {
+			err := apperror.NewAppError(errors.New("oops"), tt.category, nil)
+			if got := connecterr.ToConnectError(err).Code(); got != tt.want {
+				t.Errorf("Code() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestToConnectErrorDetails(t *testing.T) {
+	err := apperror.NotFound(errors.New("user not found")).WithResource("user")
+
+	connectErr := connecterr.ToConnectError(err)
+	if connectErr.Message() != "user not found" {
+		t.Errorf("Message() = %q, want the public message", connectErr.Message())
+	}
+	details := connectErr.Details()
+	if len(details) != 1 {
+		t.Fatalf("Details() = %v, want one detail", details)
+	}
+	value, valueErr := details[0].Value()
+	if valueErr != nil {
+		t.Fatal(valueErr)
+	}
+	s, ok := value.(*structpb.Struct)
+	if !ok || s.GetFields()["resource"].GetStringValue() != "user" {
+		t.Errorf("detail = %v, want the metadata", value)
+	}
+}
+
+func TestToConnectErrorPlain(t *testing.T) {
+	if got := connecterr.ToConnectError(nil); got != nil {
+		t.Errorf("ToConnectError(nil) = %v, want nil", got)
+	}
+
+	plain := connecterr.ToConnectError(errors.New("database password is hunter2"))
+	if plain.Code() != connect.CodeInternal || plain.Message() != "Internal Server Error" {
+		t.Errorf("plain error = %v, %q, want a generic internal error", plain.Code(), plain.Message())
+	}
+	if got := connecterr.ToConnectError(context.Canceled).Code(); got != connect.CodeCanceled {
+		t.Errorf("context.Canceled code = %v, want %v", got, connect.CodeCanceled)
+	}
+}
+
+func TestToConnectErrorInternal(t *testing.T) {
+	got := connecterr.ToConnectError(apperror.InternalServerError(errors.New("database password is hunter2")))
+	if got.Code() != connect.CodeInternal || got.Message() != "Internal Server Error" {
+		t.Errorf("internal error = %v, %q, want a generic internal error", got.Code(), got.Message())
+	}
+	if strings.Contains(got.Error(), "hunter2") {
+		t.Errorf("Error() = %q, leaks the wrapped error", got.Error())
+	}
+}
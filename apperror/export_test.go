@@ -0,0 +1,8 @@
+package apperror
+
+// These hooks expose the unexported registry resets to the apperror_test package.
+var (
+	UnregisterCategoryAlias = unregisterCategoryAlias
+	UnregisterCode          = unregisterCode
+	ResetStackSamples       = resetStackSamples
+)
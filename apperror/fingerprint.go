@@ -0,0 +1,23 @@
+package apperror
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+)
+
+// Fingerprint returns a stable hex digest identifying "the same error" for deduplication.
+// It hashes the category, the service-namespaced internal code, the message and the
+// "field" metadata, which tells validation errors apart, and ignores volatile metadata
+// such as the request id.
+func (err AppError) Fingerprint() string {
+	h := sha256.New()
+	h.Write([]byte(strconv.Itoa(int(err.Code.Category))))
+	h.Write([]byte{0})
+	h.Write([]byte(err.Code.String()))
+	h.Write([]byte{0})
+	h.Write([]byte(err.PublicMessage()))
+	h.Write([]byte{0})
+	h.Write([]byte(err.Metadata["field"]))
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,47 @@
+package apperror
+
+import (
+	"errors"
+	"maps"
+)
+
+// Flatten collapses a chain of wrapped AppErrors, e.g. one added by each layer, into a
+// single AppError. The category, status, severity and public message are taken from the
+// outermost AppError, the operations of every layer are concatenated outermost first,
+// the metadata is merged with the innermost value winning on conflicts, and the wrapped
+// error is the cause of the innermost AppError. Errors without any AppError in their
+// chain are classified with Classify. It returns nil if err is nil.
+func Flatten(err error) *AppError {
+	var layers []*AppError
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		if appErr, ok := e.(*AppError); ok {
+			layers = append(layers, appErr)
+		}
+	}
+	if len(layers) == 0 {
+		return Classify(err)
+	}
+
+	outer, inner := layers[0], layers[len(layers)-1]
+	flat := &AppError{
+		Err:        inner.Err,
+		Status:     outer.HTTPStatus(),
+		StatusText: outer.StatusText,
+		Code:       outer.Code,
+		Message:    outer.PublicMessage(),
+		Severity:   outer.Severity,
+		Details:    outer.Details,
+		Stack:      inner.Stack,
+		Errors:     outer.Errors,
+	}
+	for _, layer := range layers {
+		flat.Ops = append(flat.Ops, layer.Ops...)
+		if len(layer.Metadata) > 0 {
+			if flat.Metadata == nil {
+				flat.Metadata = make(map[string]string)
+			}
+			maps.Copy(flat.Metadata, layer.Metadata)
+		}
+	}
+	return flat
+}
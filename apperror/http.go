@@ -0,0 +1,73 @@
+package apperror
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// HTTPError is a drop-in replacement for http.Error. When err is or wraps an AppError,
+// it writes its JSON encoding with its HTTP status and the headers set by WriteHeaders. Otherwise
+// it behaves like http.Error with a 500 status and the generic status text, so the
+// message of err is not exposed to clients.
+func HTTPError(w http.ResponseWriter, err error) {
+	var appErr *AppError
+	if !errors.As(err, &appErr) {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	body, marshalErr := json.Marshal(appErr)
+	if marshalErr != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	h := w.Header()
+	h.Set("Content-Type", "application/json")
+	h.Set("X-Content-Type-Options", "nosniff")
+	status := appErr.HTTPStatus()
+	appErr.WriteHeaders(h, status)
+	w.WriteHeader(status)
+	_, _ = w.Write(body)
+}
+
+// WriteHeaders sets the response headers derived from the AppError for a response with
+// the given status: Allow for a 405, WWW-Authenticate for a 401 ("Bearer" by default),
+// Location for a 409 or a redirect, Cache-Control, the X-RateLimit headers and Retry-After.
+func (err AppError) WriteHeaders(h http.Header, status int) {
+	if allow := err.Metadata["allow"]; allow != "" && status == http.StatusMethodNotAllowed {
+		h.Set("Allow", allow)
+	}
+
+	if status == http.StatusUnauthorized {
+		challenge := err.Metadata["challenge"]
+		if challenge == "" {
+			challenge = "Bearer"
+		}
+		h.Set("WWW-Authenticate", challenge)
+	}
+
+	if location := err.Metadata["location"]; location != "" && (status == http.StatusConflict || status/100 == 3) {
+		h.Set("Location", location)
+	}
+
+	if cacheControl := err.CacheControl(); cacheControl != "" {
+		h.Set("Cache-Control", cacheControl)
+	}
+
+	if limit := err.Metadata["rate_limit"]; limit != "" {
+		h.Set("X-RateLimit-Limit", limit)
+		h.Set("X-RateLimit-Remaining", err.Metadata["rate_limit_remaining"])
+	}
+	if reset, parseErr := strconv.ParseInt(err.Metadata["rate_limit_reset"], 10, 64); parseErr == nil {
+		h.Set("X-RateLimit-Reset", strconv.FormatInt(reset, 10))
+		retryAfter := max(0, reset-time.Now().Unix())
+		h.Set("Retry-After", strconv.FormatInt(retryAfter, 10))
+	}
+	if retryAfter, parseErr := strconv.ParseInt(err.Metadata["retry_after"], 10, 64); parseErr == nil {
+		h.Set("Retry-After", strconv.FormatInt(max(0, retryAfter), 10))
+	}
+}
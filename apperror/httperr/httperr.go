@@ -0,0 +1,336 @@
+// Package httperr renders errors returned by HTTP handlers as JSON responses.
+package httperr
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/ckminhano/golib/apperror"
+)
+
+// HandlerFunc is an HTTP handler that can return an error.
+// A returned error is rendered by the handler built with Handle.
+type HandlerFunc func(w http.ResponseWriter, r *http.Request) error
+
+// StatusTextWriter is implemented by response writers that can write a custom reason
+// phrase in the status line. The standard library writers always use http.StatusText.
+type StatusTextWriter interface {
+	WriteStatusText(code int, text string)
+}
+
+// Option configures the handler built with Handle.
+type Option func(*config)
+
+type config struct {
+	logger        *slog.Logger
+	envelope      bool
+	debug         bool
+	negotiation   bool
+	maxChainDepth int
+	contextFields []any
+}
+
+// defaultMaxChainDepth is the default number of wrapped errors logged in the cause chain.
+const defaultMaxChainDepth = 10
+
+// WithLogger sets the logger used to log returned errors. It defaults to slog.Default().
+// Errors are sampled according to apperror.SampleRate.
+func WithLogger(logger *slog.Logger) Option {
+	return func(c *config) {
+		c.logger = logger
+	}
+}
+
+// WithEnvelope renders errors nested under an "error" key instead of at the top level.
+func WithEnvelope() Option {
+	return func(c *config) {
+		c.envelope = true
+	}
+}
+
+// WithDebug renders errors with their full cause chain using MarshalDebugJSON.
+// It exposes internal details and must never be enabled in production.
+func WithDebug() Option {
+	return func(c *config) {
+		c.debug = true
+	}
+}
+
+// WithContentNegotiation renders errors according to the request Accept header:
+// application/problem+json renders ProblemJSON, text/plain renders the SafeMessage,
+// and application/json or anything else renders JSON. The media type with the highest
+// q-value wins, and responses carry a "Vary: Accept" header so caches keep the formats apart.
+func WithContentNegotiation() Option {
+	return func(c *config) {
+		c.negotiation = true
+	}
+}
+
+// WithMaxChainDepth sets how many wrapped errors of the cause chain are logged, 10 by default.
+// Deeper errors are replaced by a single "..." entry. A negative n is treated as 0.
+func WithMaxChainDepth(n int) Option {
+	return func(c *config) {
+		c.maxChainDepth = max(0, n)
+	}
+}
+
+// WithContextFields copies the values of the request context stored under keys into the
+// metadata of the rendered error, e.g. the request id or the user id, so every error
+// response carries tracing information. The metadata key is the key formatted with
+// fmt.Sprint, so keys should be strings or string types such as `type ctxKey string`.
+// Missing values are skipped and existing metadata is never overwritten.
+func WithContextFields(keys ...any) Option {
+	return func(c *config) {
+		c.contextFields = append(c.contextFields, keys...)
+	}
+}
+
+// Handle adapts h into an http.Handler.
+// Errors returned by h are logged and rendered as JSON. An AppError is rendered with its
+// HTTP status and metadata, while any other error is rendered as a generic 500 response.
+// An errors.Join result is rendered as a multi-error listing every sub-error.
+func Handle(h HandlerFunc, opts ...Option) http.Handler {
+	c := newConfig(opts)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := h(w, r); err != nil {
+			c.write(w, r, err)
+		}
+	})
+}
+
+// Recover wraps next so that panics are recovered into an ErrInternal AppError with
+// the stack captured, logged and rendered as a 500 response. It is meant to be the
+// outermost middleware. A panic with http.ErrAbortHandler is re-panicked so the
+// server can abort the response.
+func Recover(next http.Handler, opts ...Option) http.Handler {
+	c := newConfig(opts)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			v := recover()
+			if v == nil {
+				return
+			}
+			if v == http.ErrAbortHandler {
+				panic(v)
+			}
+			c.write(w, r, apperror.Recovered(v))
+		}()
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{
+		logger:        slog.Default(),
+		maxChainDepth: defaultMaxChainDepth,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// errorHeaders lists the headers set by apperror.AppError.WriteHeaders, removed when the
+// error response cannot be rendered so the fallback 500 does not carry them.
+var errorHeaders = []string{
+	"Allow",
+	"WWW-Authenticate",
+	"Location",
+	"Cache-Control",
+	"X-RateLimit-Limit",
+	"X-RateLimit-Remaining",
+	"X-RateLimit-Reset",
+	"Retry-After",
+}
+
+func (c *config) write(w http.ResponseWriter, r *http.Request, err error) {
+	appErr := c.enrich(r, toAppError(err))
+	status := appErr.HTTPStatus()
+
+	c.log(r, status, appErr, err)
+
+	contentType, body, marshalErr := c.render(r, appErr)
+	if marshalErr != nil {
+		c.logger.ErrorContext(r.Context(), "marshal error response", "error", marshalErr)
+		for _, name := range errorHeaders {
+			w.Header().Del(name)
+		}
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	appErr.WriteHeaders(w.Header(), status)
+	if c.negotiation {
+		w.Header().Add("Vary", "Accept")
+	}
+	w.Header().Set("Content-Type", contentType)
+	if stw, ok := w.(StatusTextWriter); ok && appErr.StatusText != "" {
+		stw.WriteStatusText(status, appErr.StatusText)
+	} else {
+		w.WriteHeader(status)
+	}
+	_, _ = w.Write(body)
+}
+
+// enrich returns err with the request context values of the configured fields added to its metadata.
+func (c *config) enrich(r *http.Request, err *apperror.AppError) *apperror.AppError {
+	for _, key := range c.contextFields {
+		value := r.Context().Value(key)
+		if value == nil {
+			continue
+		}
+		name := fmt.Sprint(key)
+		if _, ok := err.Metadata[name]; !ok {
+			err = err.WithMetadata(name, fmt.Sprint(value))
+		}
+	}
+	return err
+}
+
+// toAppError converts err into the AppError to render. A joined error becomes a
+// multi-error, and errors that are not an AppError become a generic 500 error so
+// their message is not exposed to clients.
+func toAppError(err error) *apperror.AppError {
+	if appErr, ok := err.(*apperror.AppError); ok {
+		return appErr
+	}
+
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		var errs []error
+		for _, sub := range joined.Unwrap() {
+			if sub != nil {
+				errs = append(errs, toAppError(sub))
+			}
+		}
+		if multi := apperror.Join(errs...); multi != nil {
+			return multi
+		}
+	}
+
+	var appErr *apperror.AppError
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+
+	appErr = apperror.NewAppError(err, apperror.ErrInternal, nil)
+	appErr.Status = http.StatusInternalServerError
+	appErr.Message = http.StatusText(http.StatusInternalServerError)
+	return appErr
+}
+
+// render returns the content type and body of the error response.
+func (c *config) render(r *http.Request, err *apperror.AppError) (string, []byte, error) {
+	if c.negotiation {
+		switch negotiate(r.Header.Get("Accept")) {
+		case "application/problem+json":
+			body, marshalErr := err.ProblemJSON()
+			return "application/problem+json", body, marshalErr
+		case "text/plain":
+			return "text/plain; charset=utf-8", []byte(err.SafeMessage()), nil
+		}
+	}
+
+	body, marshalErr := c.marshal(err)
+	return "application/json", body, marshalErr
+}
+
+// negotiate returns the media type of the Accept header with the highest q-value that can
+// be rendered, preferring the first listed on ties. A wildcard such as */* renders JSON,
+// and application/json is also returned when nothing listed can be rendered.
+func negotiate(accept string) string {
+	best, bestQ := "application/json", 0.0
+	for _, mediaRange := range strings.Split(accept, ",") {
+		mediaType, params, _ := strings.Cut(mediaRange, ";")
+		switch mediaType = strings.TrimSpace(mediaType); mediaType {
+		case "*/*", "application/*":
+			mediaType = "application/json"
+		case "text/*":
+			mediaType = "text/plain"
+		case "application/json", "application/problem+json", "text/plain":
+		default:
+			continue
+		}
+		if q := quality(params); q > bestQ {
+			best, bestQ = mediaType, q
+		}
+	}
+	return best
+}
+
+// quality returns the q-value of the parameters of a media range, 1 when it is not set
+// and 0 when it is invalid.
+func quality(params string) float64 {
+	for _, param := range strings.Split(params, ";") {
+		name, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+		if !strings.EqualFold(name, "q") {
+			continue
+		}
+		q, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || q < 0 || q > 1 {
+			return 0
+		}
+		return q
+	}
+	return 1
+}
+
+func (c *config) marshal(err *apperror.AppError) ([]byte, error) {
+	if !c.debug {
+		if c.envelope {
+			return err.MarshalEnvelopeJSON()
+		}
+		return json.Marshal(err)
+	}
+
+	body, marshalErr := err.MarshalDebugJSON()
+	if marshalErr != nil || !c.envelope {
+		return body, marshalErr
+	}
+	return json.Marshal(map[string]json.RawMessage{"error": body})
+}
+
+// log logs the rendered AppError err, with the cause chain of the error returned by the handler.
+func (c *config) log(r *http.Request, status int, err *apperror.AppError, returned error) {
+	if !apperror.ShouldLog(err) {
+		return
+	}
+
+	level := slog.LevelWarn
+	if status >= http.StatusInternalServerError {
+		level = slog.LevelError
+	}
+	c.logger.Log(r.Context(), level, "request failed",
+		"method", r.Method,
+		"path", r.URL.Path,
+		"status", status,
+		"error", err,
+		"chain", causeChain(returned, c.maxChainDepth),
+	)
+}
+
+// causeChain returns the message of each error in the unwrap chain of err, outermost
+// first, stopping after depth errors with a final "..." entry. The first AppError of the
+// chain is skipped since it is logged on its own, but the layers wrapping it are kept.
+func causeChain(err error, depth int) []string {
+	var messages []string
+	skipped := false
+	for ; err != nil; err = errors.Unwrap(err) {
+		if _, ok := err.(*apperror.AppError); ok && !skipped {
+			skipped = true
+			continue
+		}
+		if len(messages) == depth {
+			return append(messages, "...")
+		}
+		messages = append(messages, err.Error())
+	}
+	return messages
+}
@@ -0,0 +1,471 @@
+package httperr_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ckminhano/golib/apperror"
+	"github.com/ckminhano/golib/apperror/httperr"
+)
+
+var discard = httperr.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+func serve(t *testing.T, h httperr.HandlerFunc, opts ...httperr.Option) *httptest.ResponseRecorder {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	httperr.Handle(h, append([]httperr.Option{discard}, opts...)...).ServeHTTP(rec, req)
+	return rec
+}
+
+func TestHandleMethodNotAllowed(t *testing.T) {
+	rec := serve(t, func(w http.ResponseWriter, r *http.Request) error {
+		return apperror.MethodNotAllowed(errors.New("method not allowed"), http.MethodGet, http.MethodPost)
+	})
+
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusMethodNotAllowed)
+	}
+	if got := rec.Header().Get("Allow"); got != "GET, POST" {
+		t.Errorf("Allow = %q, want %q", got, "GET, POST")
+	}
+}
+
+func TestHandleLocation(t *testing.T) {
+	conflict := apperror.NewAppError(errors.New("user already exists"), apperror.ErrValidation, nil)
+	conflict.Status = http.StatusConflict
+
+	tests := []struct {
+		name string
+		err  *apperror.AppError
+		want string
+	}{
+		{"conflict", conflict.WithLocation("/users/42"), "/users/42"},
+		{"conflict without location", conflict, ""},
+		{"not found", apperror.NotFound(errors.New("missing")).WithLocation("/users/42"), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(t, func(w http.ResponseWriter, r *http.Request) error {
+				return tt.err
+			})
+			if got := rec.Header().Get("Location"); got != tt.want {
+				t.Errorf("Location = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandleChallenge(t *testing.T) {
+	tests := []struct {
+		name string
+		err  *apperror.AppError
+		want string
+	}{
+		{"default", apperror.Unauthorized(errors.New("token expired")), "Bearer"},
+		{"realm", apperror.Unauthorized(errors.New("token expired")).WithChallenge("Bearer", "api"), `Bearer realm="api"`},
+		{"scheme only", apperror.Unauthorized(errors.New("login required")).WithChallenge("Basic", ""), "Basic"},
+		{"not a 401", apperror.Forbidden(errors.New("denied")).WithChallenge("Bearer", "api"), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(t, func(w http.ResponseWriter, r *http.Request) error {
+				return tt.err
+			})
+			if got := rec.Header().Get("WWW-Authenticate"); got != tt.want {
+				t.Errorf("WWW-Authenticate = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandleRetryAfter(t *testing.T) {
+	rec := serve(t, func(w http.ResponseWriter, r *http.Request) error {
+		return apperror.Unavailable(errors.New("database is down")).WithRetry(2 * time.Minute)
+	})
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusServiceUnavailable)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "120" {
+		t.Errorf("Retry-After = %q, want 120", got)
+	}
+
+	rec = serve(t, func(w http.ResponseWriter, r *http.Request) error {
+		return apperror.Unavailable(errors.New("database is down"))
+	})
+	if got := rec.Header().Get("Retry-After"); got != "" {
+		t.Errorf("Retry-After without WithRetry = %q, want none", got)
+	}
+}
+
+type ctxKey string
+
+func TestHandleContextFields(t *testing.T) {
+	base := apperror.NotFound(errors.New("missing")).WithRequestID("from-handler")
+	h := httperr.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		return base
+	}, discard, httperr.WithContextFields(ctxKey("request_id"), ctxKey("user_id"), ctxKey("tenant")))
+
+	ctx := context.WithValue(context.Background(), ctxKey("request_id"), "from-context")
+	ctx = context.WithValue(ctx, ctxKey("user_id"), 42)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx))
+
+	var got struct {
+		Metadata map[string]string `json:"metadata"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"request_id": "from-handler", "user_id": "42"}
+	if !maps.Equal(got.Metadata, want) {
+		t.Errorf("metadata = %v, want %v", got.Metadata, want)
+	}
+	if _, ok := base.Metadata["user_id"]; ok {
+		t.Error("the returned error was modified")
+	}
+}
+
+func TestHandlePlainError(t *testing.T) {
+	rec := serve(t, func(w http.ResponseWriter, r *http.Request) error {
+		return errors.New("database password is hunter2")
+	})
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusInternalServerError)
+	}
+	if got := rec.Header().Get("Content-Type"); got != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", got)
+	}
+	if body := rec.Body.String(); body == "" || strings.Contains(body, "hunter2") {
+		t.Errorf("body %q should be a generic error", body)
+	}
+}
+
+func TestHandleEnvelope(t *testing.T) {
+	h := func(w http.ResponseWriter, r *http.Request) error {
+		return apperror.NewAppError(errors.New("boom"), apperror.ErrNotFound, nil)
+	}
+
+	tests := []struct {
+		name string
+		opts []httperr.Option
+		want string
+	}{
+		{"flat", nil, `{"message":"boom","category":"not_found"}`},
+		{"enveloped", []httperr.Option{httperr.WithEnvelope()}, `{"error":{"message":"boom","category":"not_found"}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(t, h, tt.opts...)
+			if got := rec.Body.String(); got != tt.want {
+				t.Errorf("body = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandleDebug(t *testing.T) {
+	h := func(w http.ResponseWriter, r *http.Request) error {
+		return apperror.NewAppError(fmt.Errorf("service: %w", errors.New("root")), apperror.ErrInternal, nil)
+	}
+
+	if body := serve(t, h).Body.String(); strings.Contains(body, "chain") {
+		t.Errorf("body %s should not include the chain by default", body)
+	}
+
+	want := `{"error":{"message":"service: root","category":"InternalError","chain":["service: root","root"]}}`
+	if body := serve(t, h, httperr.WithDebug(), httperr.WithEnvelope()).Body.String(); body != want {
+		t.Errorf("body = %s, want %s", body, want)
+	}
+}
+
+func TestRecover(t *testing.T) {
+	var logs bytes.Buffer
+	logger := httperr.WithLogger(slog.New(slog.NewJSONHandler(&logs, nil)))
+
+	panicking := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("nil map write")
+	})
+
+	rec := httptest.NewRecorder()
+	httperr.Recover(panicking, logger).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusInternalServerError)
+	}
+	if got := rec.Header().Get("Content-Type"); got != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", got)
+	}
+	if body := rec.Body.String(); strings.Contains(body, "nil map write") {
+		t.Errorf("body %s should not expose the panic value", body)
+	}
+	if !strings.Contains(logs.String(), "panic: nil map write") {
+		t.Errorf("logs %s do not contain the panic value", logs.String())
+	}
+	if !strings.Contains(logs.String(), "TestRecover") {
+		t.Errorf("logs %s do not contain the captured stack", logs.String())
+	}
+}
+
+func TestRecoverAbortHandler(t *testing.T) {
+	aborting := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	})
+
+	defer func() {
+		if v := recover(); v != http.ErrAbortHandler {
+			t.Errorf("recovered %v, want http.ErrAbortHandler", v)
+		}
+	}()
+	httperr.Recover(aborting, discard).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+}
+
+func TestHandleCacheControl(t *testing.T) {
+	tests := []struct {
+		category apperror.Category
+		want     string
+	}{
+		{apperror.ErrInternal, "no-store"},
+		{apperror.ErrTimeout, "no-store"},
+		{apperror.ErrUnauthorized, "no-store"},
+		{apperror.ErrForbidden, "no-store"},
+		{apperror.ErrValidation, ""},
+		{apperror.ErrNotFound, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.category.String(), func(t *testing.T) {
+			rec := serve(t, func(w http.ResponseWriter, r *http.Request) error {
+				return apperror.NewAppError(errors.New("boom"), tt.category, nil)
+			})
+			if got := rec.Header().Get("Cache-Control"); got != tt.want {
+				t.Errorf("Cache-Control = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+type statusLineRecorder struct {
+	*httptest.ResponseRecorder
+	statusLine string
+}
+
+func (r *statusLineRecorder) WriteStatusText(code int, text string) {
+	r.statusLine = fmt.Sprintf("HTTP/1.1 %d %s", code, text)
+	r.WriteHeader(code)
+}
+
+func TestHandleStatusText(t *testing.T) {
+	h := httperr.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		return apperror.NewAppError(errors.New("quota"), apperror.ErrForbidden, nil).WithStatusText("Quota Exceeded")
+	}, discard)
+
+	rec := &statusLineRecorder{ResponseRecorder: httptest.NewRecorder()}
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if want := "HTTP/1.1 403 Quota Exceeded"; rec.statusLine != want {
+		t.Errorf("status line = %q, want %q", rec.statusLine, want)
+	}
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+}
+
+func TestHandleJoinedErrors(t *testing.T) {
+	rec := serve(t, func(w http.ResponseWriter, r *http.Request) error {
+		return errors.Join(
+			apperror.NewAppError(errors.New("name is required"), apperror.ErrValidation, nil).WithField("name"),
+			apperror.NewAppError(errors.New("age must be positive"), apperror.ErrValidation, nil).WithField("age"),
+		)
+	})
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+
+	var body struct {
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if len(body.Errors) != 2 || body.Errors[0].Message != "name is required" || body.Errors[1].Message != "age must be positive" {
+		t.Errorf("errors = %+v, want both validation errors", body.Errors)
+	}
+}
+
+func TestHandleJoinedPlainError(t *testing.T) {
+	rec := serve(t, func(w http.ResponseWriter, r *http.Request) error {
+		return errors.Join(
+			apperror.NewAppError(errors.New("name is required"), apperror.ErrValidation, nil),
+			errors.New("database password is hunter2"),
+		)
+	})
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusInternalServerError)
+	}
+	if body := rec.Body.String(); strings.Contains(body, "hunter2") || !strings.Contains(body, "name is required") {
+		t.Errorf("body %s should list the AppError and hide the plain error", body)
+	}
+}
+
+func TestHandleRateLimit(t *testing.T) {
+	reset := time.Now().Add(30 * time.Second)
+
+	rec := serve(t, func(w http.ResponseWriter, r *http.Request) error {
+		return apperror.TooManyRequests(errors.New("slow down")).WithRateLimit(100, 0, reset)
+	})
+
+	if rec.Code != http.StatusTooManyRequests {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusTooManyRequests)
+	}
+	want := map[string]string{
+		"X-RateLimit-Limit":     "100",
+		"X-RateLimit-Remaining": "0",
+		"X-RateLimit-Reset":     strconv.FormatInt(reset.Unix(), 10),
+	}
+	for k, v := range want {
+		if got := rec.Header().Get(k); got != v {
+			t.Errorf("%s = %q, want %q", k, got, v)
+		}
+	}
+	retryAfter, err := strconv.Atoi(rec.Header().Get("Retry-After"))
+	if err != nil || retryAfter < 29 || retryAfter > 30 {
+		t.Errorf("Retry-After = %q, want about 30 seconds", rec.Header().Get("Retry-After"))
+	}
+}
+
+func TestHandleContentNegotiation(t *testing.T) {
+	h := httperr.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		return apperror.NewAppError(errors.New("user not found"), apperror.ErrNotFound, nil)
+	}, discard, httperr.WithContentNegotiation())
+
+	tests := []struct {
+		accept      string
+		contentType string
+		bodyPrefix  string
+	}{
+		{"application/json", "application/json", `{"message":"user not found"`},
+		{"application/problem+json", "application/problem+json", `{"type":"/errors/not_found"`},
+		{"text/plain", "text/plain; charset=utf-8", "user not found"},
+		{"text/html;q=0.9, text/plain;q=0.8", "text/plain; charset=utf-8", "user not found"},
+		{"text/plain;q=0.1, application/problem+json;q=0.9", "application/problem+json", `{"type":"/errors/not_found"`},
+		{"text/plain;q=0, */*;q=0.5", "application/json", `{"message":"user not found"`},
+		{"*/*", "application/json", `{"message":"user not found"`},
+		{"", "application/json", `{"message":"user not found"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.accept, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Accept", tt.accept)
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if got := rec.Header().Get("Content-Type"); got != tt.contentType {
+				t.Errorf("Content-Type = %q, want %q", got, tt.contentType)
+			}
+			if body := rec.Body.String(); !strings.HasPrefix(body, tt.bodyPrefix) {
+				t.Errorf("body = %s, want prefix %s", body, tt.bodyPrefix)
+			}
+			if got := rec.Header().Get("Vary"); got != "Accept" {
+				t.Errorf("Vary = %q, want Accept", got)
+			}
+		})
+	}
+}
+
+func TestHandleContentNegotiationProduction(t *testing.T) {
+	h := httperr.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		return apperror.NewAppError(errors.New("token replay for user 7"), apperror.ErrSecurity, nil)
+	}, discard, httperr.WithContentNegotiation())
+
+	for _, accept := range []string{"application/json", "application/problem+json", "text/plain"} {
+		t.Run(accept, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Accept", accept)
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			body := rec.Body.String()
+			if strings.Contains(body, "user 7") || strings.Contains(body, "SecurityError") {
+				t.Errorf("body = %s, want no internal details", body)
+			}
+		})
+	}
+}
+
+func TestHandleMaxChainDepth(t *testing.T) {
+	err := errors.New("root")
+	for i := range 20 {
+		err = fmt.Errorf("layer %d: %w", i, err)
+	}
+
+	for _, tt := range []struct {
+		name  string
+		opts  []httperr.Option
+		depth int
+	}{
+		{"default", nil, 10},
+		{"configured", []httperr.Option{httperr.WithMaxChainDepth(3)}, 3},
+		{"negative", []httperr.Option{httperr.WithMaxChainDepth(-1)}, 0},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			opts := append([]httperr.Option{httperr.WithLogger(slog.New(slog.NewJSONHandler(&logs, nil)))}, tt.opts...)
+			h := httperr.Handle(func(w http.ResponseWriter, r *http.Request) error {
+				return apperror.NewAppError(err, apperror.ErrInternal, nil)
+			}, opts...)
+			h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+			var record struct {
+				Chain []string `json:"chain"`
+			}
+			if err := json.Unmarshal(logs.Bytes(), &record); err != nil {
+				t.Fatal(err)
+			}
+			if len(record.Chain) != tt.depth+1 || record.Chain[tt.depth] != "..." {
+				t.Errorf("chain = %q, want %d entries followed by \"...\"", record.Chain, tt.depth)
+			}
+		})
+	}
+}
+
+func TestHandleChainOuterLayers(t *testing.T) {
+	var logs bytes.Buffer
+	h := httperr.Handle(func(w http.ResponseWriter, r *http.Request) error {
+		err := apperror.NewAppError(errors.New("root"), apperror.ErrInternal, nil)
+		return fmt.Errorf("handler: %w", fmt.Errorf("load user: %w", err))
+	}, httperr.WithLogger(slog.New(slog.NewJSONHandler(&logs, nil))))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	var record struct {
+		Chain []string `json:"chain"`
+	}
+	if err := json.Unmarshal(logs.Bytes(), &record); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"handler: load user: root", "load user: root", "root"}
+	if !slices.Equal(record.Chain, want) {
+		t.Errorf("chain = %q, want %q", record.Chain, want)
+	}
+}
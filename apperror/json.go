@@ -0,0 +1,212 @@
+package apperror
+
+import (
+	"encoding/json"
+	"errors"
+	"maps"
+	"slices"
+)
+
+// MaxMetadataBytes limits the serialized size of the metadata in MarshalJSON and ProblemJSON.
+// Entries are kept in key order until the limit is reached, and the rest are dropped with
+// "metadata_truncated" set to true. A value <= 0 disables the limit.
+var MaxMetadataBytes = 16 << 10
+
+// jsonError is the wire representation of an AppError.
+// DetailsError records why Details could not be marshaled.
+// Cause and Stack are only set in ModeDevelopment, and Severity, Retryable and Ops are
+// left out in ModeProduction.
+type jsonError struct {
+	Message      string            `json:"message"`
+	Status       int               `json:"status,omitempty"`
+	Category     string            `json:"category"`
+	Code         int               `json:"code,omitempty"`
+	CodeName     string            `json:"code_name,omitempty"`
+	Service      string            `json:"service,omitempty"`
+	Metadata     map[string]string `json:"metadata,omitempty"`
+	Truncated    bool              `json:"metadata_truncated,omitempty"`
+	Severity     Severity          `json:"severity,omitempty"`
+	Retryable    bool              `json:"retryable,omitempty"`
+	Ops          []string          `json:"ops,omitempty"`
+	Details      json.RawMessage   `json:"details,omitempty"`
+	DetailsError string            `json:"details_error,omitempty"`
+	Chain        []string          `json:"chain,omitempty"`
+	Cause        string            `json:"cause,omitempty"`
+	Stack        string            `json:"stack,omitempty"`
+	Errors       []jsonError       `json:"errors,omitempty"`
+}
+
+// MarshalJSON implements the json.Marshaler interface for AppError.
+// In ModeProduction the category is rendered with PublicCategory and the message with
+// SafeMessage. In ModeDevelopment the internal category name is kept and the wrapped
+// error and the stack are rendered under the "cause" and "stack" keys.
+func (err AppError) MarshalJSON() ([]byte, error) {
+	return json.Marshal(err.render())
+}
+
+// render returns the wire representation of the AppError according to Mode.
+func (err AppError) render() jsonError {
+	payload := err.toJSON()
+	if Mode == ModeDevelopment {
+		if err.Err != nil {
+			payload.Cause = err.Err.Error()
+		}
+		if len(err.Stack) > 0 {
+			payload.Stack = formatStack(err.Stack)
+		}
+	} else {
+		err.publicize(&payload)
+	}
+	return payload
+}
+
+// publicize replaces the categories and messages of payload and its sub-errors with their
+// public names and SafeMessage, and drops the fields only meant for internal use.
+func (err AppError) publicize(payload *jsonError) {
+	payload.Category = err.PublicCategory()
+	payload.Message = err.SafeMessage()
+	payload.Severity = 0
+	payload.Retryable = false
+	payload.Ops = nil
+	for i, sub := range err.Errors {
+		sub.publicize(&payload.Errors[i])
+	}
+}
+
+func (err AppError) toJSON() jsonError {
+	metadata, truncated := truncateMetadata(err.Metadata, MaxMetadataBytes)
+	payload := jsonError{
+		Message:   err.PublicMessage(),
+		Status:    err.Status,
+		Category:  err.Code.Category.String(),
+		Code:      int(err.Code.Internal),
+		CodeName:  err.Code.Name(),
+		Service:   err.Code.Service,
+		Metadata:  metadata,
+		Truncated: truncated,
+		Severity:  err.Severity,
+		Retryable: err.Retryable,
+		Ops:       err.Ops,
+	}
+
+	for _, sub := range err.Errors {
+		payload.Errors = append(payload.Errors, sub.toJSON())
+	}
+
+	if err.Details != nil {
+		details, marshalErr := json.Marshal(err.Details)
+		if marshalErr != nil {
+			payload.DetailsError = marshalErr.Error()
+		} else {
+			payload.Details = details
+		}
+	}
+
+	return payload
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface for AppError.
+// A public category name, as rendered in ModeProduction, is mapped back to the first
+// category using it, so e.g. "server_error" becomes ErrInternal.
+// The wrapped error cannot be restored, so Err is set to a new error holding the message.
+// Details are kept as a json.RawMessage, and the sub-errors of a multi-error are decoded
+// the same way.
+func (err *AppError) UnmarshalJSON(data []byte) error {
+	var payload jsonError
+	if jsonErr := json.Unmarshal(data, &payload); jsonErr != nil {
+		return jsonErr
+	}
+
+	decoded, decodeErr := fromJSON(payload)
+	if decodeErr != nil {
+		return decodeErr
+	}
+	*err = *decoded
+	return nil
+}
+
+// fromJSON converts the wire representation back into an AppError, decoding its
+// sub-errors recursively.
+func fromJSON(payload jsonError) (*AppError, error) {
+	category, parseErr := ParseCategory(payload.Category)
+	if parseErr != nil {
+		category, parseErr = parsePublicCategory(payload.Category)
+	}
+	if parseErr != nil {
+		return nil, parseErr
+	}
+
+	err := &AppError{
+		Err:    errors.New(payload.Message),
+		Status: payload.Status,
+		Code: Code{
+			Category: category,
+			Internal: ErrorCode(payload.Code),
+			Service:  payload.Service,
+		},
+		Message:   payload.Message,
+		Severity:  payload.Severity,
+		Retryable: payload.Retryable,
+		Metadata:  payload.Metadata,
+		Ops:       payload.Ops,
+	}
+	if payload.Details != nil {
+		err.Details = payload.Details
+	}
+	for _, sub := range payload.Errors {
+		decoded, decodeErr := fromJSON(sub)
+		if decodeErr != nil {
+			return nil, decodeErr
+		}
+		err.Errors = append(err.Errors, decoded)
+	}
+	return err, nil
+}
+
+// MarshalEnvelopeJSON marshals the AppError nested under an "error" key,
+// e.g. {"error": {"message": "..."}}, for clients that expect an envelope.
+// The nested error is rendered like MarshalJSON.
+func (err AppError) MarshalEnvelopeJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Error jsonError `json:"error"`
+	}{err.render()})
+}
+
+// MarshalDebugJSON marshals the AppError with an additional "chain" array holding the
+// message of each wrapped error, outermost first, down to the root cause.
+// It exposes internal details and must not be used for production responses.
+func (err AppError) MarshalDebugJSON() ([]byte, error) {
+	payload := err.toJSON()
+	payload.Chain = chain(err.Err)
+	return json.Marshal(payload)
+}
+
+// chain returns the message of each error in the unwrap chain of err, outermost first.
+func chain(err error) []string {
+	var messages []string
+	for ; err != nil; err = errors.Unwrap(err) {
+		messages = append(messages, err.Error())
+	}
+	return messages
+}
+
+// truncateMetadata returns the entries of metadata, in key order, whose serialized size
+// fits in limit, and whether any entry was dropped.
+func truncateMetadata(metadata map[string]string, limit int) (map[string]string, bool) {
+	if limit <= 0 || len(metadata) == 0 {
+		return metadata, false
+	}
+
+	size := 2 // {}
+	kept := make(map[string]string, len(metadata))
+	for _, k := range slices.Sorted(maps.Keys(metadata)) {
+		key, _ := json.Marshal(k)
+		value, _ := json.Marshal(metadata[k])
+		size += len(key) + len(value) + 2 // colon and comma
+		if size > limit {
+			return kept, true
+		}
+		kept[k] = metadata[k]
+	}
+	return metadata, false
+}
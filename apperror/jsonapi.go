@@ -0,0 +1,31 @@
+package apperror
+
+import "strconv"
+
+// JSONAPIError returns the AppError as a JSON:API error object with the "status" (as a
+// string, per the specification), "code", "title", "detail" and "meta" members. The code
+// and meta members are omitted when empty. The detail is the SafeMessage, so the cause of
+// a 5xx error is not exposed in production. A multi-error returns a document with a
+// top-level "errors" array holding the object of each sub-error instead.
+func (err AppError) JSONAPIError() map[string]any {
+	if len(err.Errors) > 0 {
+		errs := make([]map[string]any, len(err.Errors))
+		for i, sub := range err.Errors {
+			errs[i] = sub.JSONAPIError()
+		}
+		return map[string]any{"errors": errs}
+	}
+
+	obj := map[string]any{
+		"status": strconv.Itoa(err.HTTPStatus()),
+		"title":  err.HTTPStatusText(),
+		"detail": err.SafeMessage(),
+	}
+	if err.Code.Internal != 0 {
+		obj["code"] = err.Code.String()
+	}
+	if len(err.Metadata) > 0 {
+		obj["meta"] = err.Metadata
+	}
+	return obj
+}
@@ -0,0 +1,67 @@
+package apperror
+
+import (
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// LogValue implements the slog.LogValuer interface for AppError.
+// The error is logged as a group with its category, HTTP status, code and metadata.
+func (err AppError) LogValue() slog.Value {
+	attrs := []slog.Attr{
+		slog.String("category", err.Code.Category.String()),
+		slog.String("severity", err.EffectiveSeverity().String()),
+		slog.Int("status", err.HTTPStatus()),
+		slog.Int("code", int(err.Code.Internal)),
+	}
+	if err.Code.Service != "" {
+		attrs = append(attrs, slog.String("service", err.Code.Service))
+	}
+	if len(err.Ops) > 0 {
+		attrs = append(attrs, slog.String("op", strings.Join(err.Ops, " -> ")))
+	}
+	if err.Err != nil {
+		attrs = append(attrs, slog.String("error", err.Err.Error()))
+	}
+	if err.Message != "" {
+		attrs = append(attrs, slog.String("message", err.Message))
+	}
+	if len(err.Metadata) > 0 {
+		metadata := make([]any, 0, len(err.Metadata))
+		for _, k := range slices.Sorted(maps.Keys(err.Metadata)) {
+			metadata = append(metadata, slog.String(k, err.Metadata[k]))
+		}
+		attrs = append(attrs, slog.Group("metadata", metadata...))
+	}
+
+	if len(err.Stack) > 0 {
+		attrs = append(attrs, slog.String("stack", formatStack(err.Stack)))
+	}
+
+	return slog.GroupValue(attrs...)
+}
+
+// SlogAttrs returns the attributes of LogValue as flat attributes whose keys are
+// prefixed with prefix, e.g. "err.category" and "err.metadata.field" for prefix "err".
+// An empty prefix returns unprefixed keys.
+func (err AppError) SlogAttrs(prefix string) []slog.Attr {
+	return flattenAttrs(prefix, err.LogValue().Group())
+}
+
+func flattenAttrs(prefix string, attrs []slog.Attr) []slog.Attr {
+	flat := make([]slog.Attr, 0, len(attrs))
+	for _, attr := range attrs {
+		key := attr.Key
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		if attr.Value.Kind() == slog.KindGroup {
+			flat = append(flat, flattenAttrs(key, attr.Value.Group())...)
+			continue
+		}
+		flat = append(flat, slog.Attr{Key: key, Value: attr.Value})
+	}
+	return flat
+}
@@ -0,0 +1,48 @@
+// Package metrics counts AppErrors by category, so tests can assert how many errors of
+// each category occurred without a full metrics registry.
+package metrics
+
+import (
+	"errors"
+	"maps"
+	"sync"
+
+	"github.com/ckminhano/golib/apperror"
+)
+
+var (
+	mu     sync.Mutex
+	counts = map[apperror.Category]int{}
+)
+
+// Record counts err under its category. Errors that are not an AppError are counted
+// as apperror.ErrInternal, and nil errors are ignored. It is safe for concurrent use.
+func Record(err error) {
+	if err == nil {
+		return
+	}
+
+	category := apperror.ErrInternal
+	var appErr *apperror.AppError
+	if errors.As(err, &appErr) {
+		category = appErr.Code.Category
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	counts[category]++
+}
+
+// Snapshot returns a copy of the number of errors recorded for each category since the last Reset.
+func Snapshot() map[apperror.Category]int {
+	mu.Lock()
+	defer mu.Unlock()
+	return maps.Clone(counts)
+}
+
+// Reset clears the recorded counts.
+func Reset() {
+	mu.Lock()
+	defer mu.Unlock()
+	clear(counts)
+}
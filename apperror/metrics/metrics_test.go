@@ -0,0 +1,52 @@
+package metrics_test
+
+import (
+	"errors"
+	"maps"
+	"sync"
+	"testing"
+
+	"github.com/ckminhano/golib/apperror"
+	"github.com/ckminhano/golib/apperror/metrics"
+)
+
+func TestSnapshotReset(t *testing.T) {
+	metrics.Reset()
+	t.Cleanup(metrics.Reset)
+
+	var wg sync.WaitGroup
+	for range 3 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			metrics.Record(apperror.NotFound(errors.New("missing")))
+		}()
+	}
+	wg.Wait()
+	metrics.Record(apperror.BadRequest(errors.New("bad")))
+	metrics.Record(errors.New("plain"))
+	metrics.Record(nil)
+
+	snapshot := metrics.Snapshot()
+	want := map[apperror.Category]int{
+		apperror.ErrNotFound:   3,
+		apperror.ErrValidation: 1,
+		apperror.ErrInternal:   1,
+	}
+	if !maps.Equal(snapshot, want) {
+		t.Errorf("Snapshot() = %v, want %v", snapshot, want)
+	}
+
+	metrics.Reset()
+	if got := metrics.Snapshot(); len(got) != 0 {
+		t.Errorf("Snapshot() after Reset() = %v, want empty", got)
+	}
+	if snapshot[apperror.ErrNotFound] != 3 {
+		t.Error("Reset() changed a previous snapshot")
+	}
+
+	metrics.Record(apperror.NotFound(errors.New("missing")))
+	if got := metrics.Snapshot()[apperror.ErrNotFound]; got != 1 {
+		t.Errorf("count after Reset() = %d, want 1", got)
+	}
+}
@@ -0,0 +1,33 @@
+package apperror
+
+import "net/http"
+
+// RenderMode controls how much internal detail MarshalJSON exposes.
+type RenderMode int
+
+const (
+	// ModeProduction renders only the public fields of an AppError, such as PublicMessage and the category.
+	ModeProduction RenderMode = iota
+	// ModeDevelopment additionally renders the wrapped error and the captured stack.
+	ModeDevelopment
+)
+
+// Mode is the RenderMode used by MarshalJSON. It defaults to ModeProduction so internal
+// details are not leaked unless explicitly enabled.
+var Mode = ModeProduction
+
+// SafeMessage returns the message to render for clients according to Mode. In ModeDevelopment
+// it is PublicMessage. In ModeProduction, a 5xx error without an explicit Message renders the
+// standard status text instead of the wrapped error message, which may hold internal details.
+// A Message copied from the wrapped error, as the helper constructors do, is not explicit.
+func (err AppError) SafeMessage() string {
+	if Mode != ModeDevelopment && err.HTTPStatus() >= http.StatusInternalServerError && !err.hasExplicitMessage() {
+		return http.StatusText(err.HTTPStatus())
+	}
+	return err.PublicMessage()
+}
+
+// hasExplicitMessage reports whether Message was set on purpose rather than copied from Err.
+func (err AppError) hasExplicitMessage() bool {
+	return err.Message != "" && (err.Err == nil || err.Message != err.Err.Error())
+}
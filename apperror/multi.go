@@ -0,0 +1,82 @@
+package apperror
+
+import (
+	"errors"
+	"fmt"
+)
+
+// NewMultiError creates an AppError with the provided category grouping several errors,
+// e.g. every validation failure of a request. The sub-errors are stored in the Errors
+// field and are also wrapped, so errors.Is and errors.As can find any of them.
+func NewMultiError(category Category, errs ...*AppError) *AppError {
+	return &AppError{
+		Err:    joinErrors(errs),
+		Status: category.HTTPStatus(),
+		Code: Code{
+			Category: category,
+		},
+		Message: multiMessage(len(errs)),
+		Errors:  errs,
+	}
+}
+
+// Join groups errs into a multi-error AppError, as NewMultiError does. Errors that are
+// not an AppError are classified with Classify, and nil errors are discarded. The
+// category is the one of the sub-error with the highest HTTP status, so a server
+// error outweighs client errors. It returns nil if every error is nil.
+func Join(errs ...error) *AppError {
+	var appErrs []*AppError
+	for _, err := range errs {
+		if appErr := Classify(err); appErr != nil {
+			appErrs = append(appErrs, appErr)
+		}
+	}
+	if len(appErrs) == 0 {
+		return nil
+	}
+
+	highest := appErrs[0]
+	for _, appErr := range appErrs[1:] {
+		if appErr.HTTPStatus() > highest.HTTPStatus() {
+			highest = appErr
+		}
+	}
+	return NewMultiError(highest.Code.Category, appErrs...)
+}
+
+// Dedupe returns a copy of the multi-error without duplicated sub-errors, as identified
+// by Fingerprint. The first occurrence of each sub-error is kept.
+func (err AppError) Dedupe() *AppError {
+	e := err.clone()
+	seen := make(map[string]bool, len(err.Errors))
+	e.Errors = e.Errors[:0]
+	for _, sub := range err.Errors {
+		fingerprint := sub.Fingerprint()
+		if seen[fingerprint] {
+			continue
+		}
+		seen[fingerprint] = true
+		e.Errors = append(e.Errors, sub)
+	}
+
+	e.Err = joinErrors(e.Errors)
+	if err.Message == multiMessage(len(err.Errors)) {
+		e.Message = multiMessage(len(e.Errors))
+	}
+	return e
+}
+
+func joinErrors(errs []*AppError) error {
+	causes := make([]error, len(errs))
+	for i, err := range errs {
+		causes[i] = err
+	}
+	return errors.Join(causes...)
+}
+
+func multiMessage(n int) string {
+	if n == 1 {
+		return "1 error occurred"
+	}
+	return fmt.Sprintf("%d errors occurred", n)
+}
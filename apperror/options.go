@@ -0,0 +1,96 @@
+package apperror
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrIncomplete is returned by Build when the AppError has neither a cause nor a message.
+var ErrIncomplete = errors.New("apperror: a cause or a message is required")
+
+// Option configures an AppError built with New or Build.
+type Option func(*AppError)
+
+// WithCause sets the wrapped error.
+func WithCause(cause error) Option {
+	return func(err *AppError) {
+		err.Err = cause
+	}
+}
+
+// WithCategory sets the error category.
+func WithCategory(category Category) Option {
+	return func(err *AppError) {
+		err.Code.Category = category
+	}
+}
+
+// WithCode sets the internal error code.
+func WithCode(code int) Option {
+	return func(err *AppError) {
+		err.Code.Internal = ErrorCode(code)
+	}
+}
+
+// WithStatus sets the HTTP status code.
+func WithStatus(status int) Option {
+	return func(err *AppError) {
+		err.Status = status
+	}
+}
+
+// WithMessage sets the error message.
+func WithMessage(message string) Option {
+	return func(err *AppError) {
+		err.Message = message
+	}
+}
+
+// New creates a new AppError configured by the provided options.
+// Use Build to also validate that the result is complete.
+func New(opts ...Option) *AppError {
+	err := &AppError{}
+	for _, opt := range opts {
+		opt(err)
+	}
+	return err
+}
+
+// Build creates a new AppError like New, but returns ErrIncomplete if the
+// AppError has neither a cause nor a message.
+func Build(opts ...Option) (*AppError, error) {
+	err := New(opts...)
+	if err.Err == nil && err.Message == "" {
+		return nil, ErrIncomplete
+	}
+	return err, nil
+}
+
+// Wrap creates a new AppError adding context to inner, with message as its message and
+// "message: inner" as its error. The category and status are inherited from inner,
+// so wrapping a NotFound error keeps it a 404; errors that are not an AppError are
+// classified with Classify. The options are applied last and override the inherited
+// values, e.g. WithCategory and WithStatus. Without WithStatus, the status follows the
+// final category, so WithCategory(ErrInternal) turns a wrapped 404 into a 500. It
+// returns nil if inner is nil.
+func Wrap(inner error, message string, opts ...Option) *AppError {
+	classified := Classify(inner)
+	if classified == nil {
+		return nil
+	}
+
+	err := &AppError{
+		Err: fmt.Errorf("%s: %w", message, inner),
+		Code: Code{
+			Category: classified.Code.Category,
+		},
+		Message: message,
+	}
+	for _, opt := range opts {
+		opt(err)
+	}
+	if err.Status == 0 && err.Code.Category == classified.Code.Category {
+		err.Status = classified.HTTPStatus()
+	}
+	return err
+}
@@ -0,0 +1,85 @@
+package apperror
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// ProblemTypeBase is the base URI used to build the RFC 7807 "type" member of
+// categories without an entry in TypeURIForCategory.
+var ProblemTypeBase = "/errors/"
+
+// TypeURIForCategory maps categories to their RFC 7807 "type" URI, e.g. the documentation
+// page of the category. Unmapped categories use ProblemTypeBase followed by the category name,
+// which is its public name unless Mode is ModeDevelopment.
+var TypeURIForCategory = map[Category]string{}
+
+// problem is the RFC 7807 application/problem+json representation of an AppError.
+// Errors holds the sub-errors that have a "field" metadata, keyed by field.
+type problem struct {
+	Type      string                  `json:"type"`
+	Title     string                  `json:"title"`
+	Status    int                     `json:"status"`
+	Detail    string                  `json:"detail,omitempty"`
+	Code      int                     `json:"code,omitempty"`
+	Service   string                  `json:"service,omitempty"`
+	Metadata  map[string]string       `json:"metadata,omitempty"`
+	Truncated bool                    `json:"metadata_truncated,omitempty"`
+	Details   json.RawMessage         `json:"details,omitempty"`
+	Errors    map[string]problemField `json:"errors,omitempty"`
+}
+
+// problemField is the error of a single field in the "errors" member of a problem.
+type problemField struct {
+	Code    string `json:"code,omitempty"`
+	Message string `json:"message"`
+}
+
+// ProblemJSON marshals the AppError as an RFC 7807 problem details object.
+// Like MarshalJSON, it uses the public category name and SafeMessage unless Mode is ModeDevelopment.
+// Sub-errors with a "field" metadata are rendered in an "errors" object as
+// {field: {code, message}}, where the code is the "field_code" metadata, or the
+// "constraint" metadata if there is none. When a field has several errors, the last one is kept.
+func (err AppError) ProblemJSON() ([]byte, error) {
+	payload := err.toJSON()
+	status := err.HTTPStatus()
+
+	return json.Marshal(problem{
+		Type:      problemType(err.Code.Category),
+		Title:     http.StatusText(status),
+		Status:    status,
+		Detail:    err.SafeMessage(),
+		Code:      payload.Code,
+		Service:   payload.Service,
+		Metadata:  payload.Metadata,
+		Truncated: payload.Truncated,
+		Details:   payload.Details,
+		Errors:    problemFields(err.Errors),
+	})
+}
+
+func problemFields(errs []*AppError) map[string]problemField {
+	var fields map[string]problemField
+	for _, sub := range errs {
+		field := sub.Metadata["field"]
+		if field == "" {
+			continue
+		}
+		if fields == nil {
+			fields = make(map[string]problemField)
+		}
+		code := sub.Metadata["field_code"]
+		if code == "" {
+			code = sub.Metadata["constraint"]
+		}
+		fields[field] = problemField{Code: code, Message: sub.SafeMessage()}
+	}
+	return fields
+}
+
+func problemType(c Category) string {
+	if uri, ok := TypeURIForCategory[c]; ok {
+		return uri
+	}
+	return ProblemTypeBase + c.renderedName()
+}
@@ -0,0 +1,29 @@
+package apperror
+
+import "errors"
+
+// Violation mirrors a buf/protovalidate violation without depending on protobuf.
+type Violation struct {
+	// FieldPath is the path of the invalid field, e.g. "user.email".
+	FieldPath string
+	// Constraint is the id of the failed constraint, e.g. "string.email".
+	Constraint string
+	Message    string
+}
+
+// FromProtoValidate converts protovalidate violations into an ErrValidation multi-error.
+// Each violation becomes a sub-error with the field path in the "field" metadata and
+// the constraint id in the "constraint" metadata. It returns nil if there are no violations.
+func FromProtoValidate(violations []Violation) *AppError {
+	if len(violations) == 0 {
+		return nil
+	}
+
+	errs := make([]*AppError, len(violations))
+	for i, v := range violations {
+		errs[i] = NewAppError(errors.New(v.Message), ErrValidation, nil).
+			WithField(v.FieldPath).
+			withMetadata("constraint", v.Constraint)
+	}
+	return NewMultiError(ErrValidation, errs...)
+}
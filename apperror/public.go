@@ -0,0 +1,54 @@
+package apperror
+
+import "fmt"
+
+// PublicCategory returns the name of the error's category in the public vocabulary
+// used by MarshalJSON in ModeProduction. It collapses categories that would reveal
+// internal details, e.g. ErrInternal and ErrSecurity are both "server_error".
+func (err AppError) PublicCategory() string {
+	return err.Code.Category.public()
+}
+
+func (c Category) public() string {
+	switch c {
+	case ErrValidation, ErrMethoNotAllowed:
+		return "invalid_request"
+	case ErrNotFound:
+		return "not_found"
+	case ErrUnauthorized:
+		return "unauthorized"
+	case ErrForbidden:
+		return "forbidden"
+	case ErrTimeout:
+		return "timeout"
+	case ErrCanceled:
+		return "canceled"
+	case ErrTooManyRequests:
+		return "rate_limited"
+	case ErrConflict:
+		return "conflict"
+	case ErrUnavailable:
+		return "unavailable"
+	default:
+		return "server_error"
+	}
+}
+
+// renderedName returns the name of the category rendered for clients: its String() in
+// ModeDevelopment and its public name otherwise.
+func (c Category) renderedName() string {
+	if Mode == ModeDevelopment {
+		return c.String()
+	}
+	return c.public()
+}
+
+// parsePublicCategory returns the first category, in declaration order, whose public name is s.
+func parsePublicCategory(s string) (Category, error) {
+	for _, c := range AllCategories() {
+		if c.public() == s {
+			return c, nil
+		}
+	}
+	return 0, fmt.Errorf("apperror: unknown category %q", s)
+}
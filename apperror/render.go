@@ -0,0 +1,17 @@
+package apperror
+
+import "regexp"
+
+var placeholder = regexp.MustCompile(`\{([^{}]+)\}`)
+
+// RenderMessage returns the public message with each {key} placeholder replaced by
+// the metadata value of key, e.g. "user {id} not found" with an "id" metadata.
+// Placeholders without a matching metadata key are left intact.
+func (err AppError) RenderMessage() string {
+	return placeholder.ReplaceAllStringFunc(err.PublicMessage(), func(match string) string {
+		if value, ok := err.Metadata[match[1:len(match)-1]]; ok {
+			return value
+		}
+		return match
+	})
+}
@@ -0,0 +1,81 @@
+package apperror
+
+import (
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// SampleRate maps categories to the probability, between 0 and 1, that ShouldLog
+// returns true for them. Categories without an entry are always logged.
+// It must not be modified while errors are being logged.
+var SampleRate = map[Category]float64{}
+
+// SampleRandom returns a pseudo-random number in [0, 1) used by ShouldLog and WithSampledStack.
+// It can be replaced in tests for deterministic sampling.
+var SampleRandom = rand.Float64
+
+// ShouldLog reports whether err must be logged according to the SampleRate of its category.
+func ShouldLog(err *AppError) bool {
+	rate, ok := SampleRate[err.Code.Category]
+	if !ok {
+		return true
+	}
+	return SampleRandom() < rate
+}
+
+// StackSampleRate makes WithSampledStack capture the stack of only 1 in StackSampleRate
+// ErrInternal errors, to bound the overhead under high load. A value <= 1 captures every stack.
+var StackSampleRate = 1
+
+// StackSampleWindow is the window in which the first occurrence of each error, by
+// Fingerprint, always captures its stack regardless of StackSampleRate.
+var StackSampleWindow = time.Minute
+
+var (
+	stackSampleMu    sync.Mutex
+	stackWindowStart time.Time
+	stackSeen        = map[string]struct{}{}
+)
+
+// WithSampledStack captures the current call stack into the AppError like WithStack,
+// except that ErrInternal errors are sampled according to StackSampleRate. The first
+// occurrence of an error, by Fingerprint, in each StackSampleWindow is always captured.
+// It is safe for concurrent use.
+func (err AppError) WithSampledStack() *AppError {
+	e := err.clone()
+	if err.captureStack() {
+		e.Stack = callers(3)
+	}
+	return e
+}
+
+// captureStack reports whether WithSampledStack must capture the stack of err.
+func (err AppError) captureStack() bool {
+	if StackSampleRate <= 1 || err.Code.Category != ErrInternal {
+		return true
+	}
+
+	fingerprint := err.Fingerprint()
+	now := time.Now()
+
+	stackSampleMu.Lock()
+	if now.Sub(stackWindowStart) >= StackSampleWindow {
+		clear(stackSeen)
+		stackWindowStart = now
+	}
+	_, seen := stackSeen[fingerprint]
+	stackSeen[fingerprint] = struct{}{}
+	stackSampleMu.Unlock()
+
+	return !seen || SampleRandom() < 1/float64(StackSampleRate)
+}
+
+// resetStackSamples forgets the errors seen in the current StackSampleWindow, so tests
+// start from an empty window.
+func resetStackSamples() {
+	stackSampleMu.Lock()
+	defer stackSampleMu.Unlock()
+	clear(stackSeen)
+	stackWindowStart = time.Time{}
+}
@@ -0,0 +1,42 @@
+package apperror
+
+import "slices"
+
+// JSONSchema returns a JSON Schema (draft 2020-12) describing the JSON produced by
+// MarshalJSON, to be included in OpenAPI specs. The category enum lists the name of
+// each category in AllCategories followed by the public names used in ModeProduction.
+func JSONSchema() map[string]any {
+	var categories []string
+	for _, c := range AllCategories() {
+		categories = append(categories, c.String())
+	}
+	for _, c := range AllCategories() {
+		if name := c.public(); !slices.Contains(categories, name) {
+			categories = append(categories, name)
+		}
+	}
+
+	str := map[string]any{"type": "string"}
+	return map[string]any{
+		"$schema":  "https://json-schema.org/draft/2020-12/schema",
+		"title":    "AppError",
+		"type":     "object",
+		"required": []string{"message", "category"},
+		"properties": map[string]any{
+			"message":            str,
+			"status":             map[string]any{"type": "integer"},
+			"category":           map[string]any{"type": "string", "enum": categories},
+			"code":               map[string]any{"type": "integer"},
+			"code_name":          str,
+			"service":            str,
+			"metadata":           map[string]any{"type": "object", "additionalProperties": str},
+			"metadata_truncated": map[string]any{"type": "boolean"},
+			"details":            map[string]any{},
+			"details_error":      str,
+			"chain":              map[string]any{"type": "array", "items": str},
+			"cause":              str,
+			"stack":              str,
+			"errors":             map[string]any{"type": "array", "items": map[string]any{"$ref": "#"}},
+		},
+	}
+}
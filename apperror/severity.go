@@ -0,0 +1,115 @@
+package apperror
+
+import (
+	"errors"
+	"sync"
+)
+
+// Severity represents how serious an error is, e.g. for alerting.
+// The zero value means the severity is not set.
+type Severity int
+
+const (
+	SeverityInfo Severity = iota + 1
+	SeverityWarning
+	SeverityError
+	SeverityCritical
+)
+
+var (
+	severityMu        sync.RWMutex
+	severityOverrides = map[Category]Severity{}
+)
+
+// EscalationThreshold is the number of occurrences from which Escalate raises an error to SeverityCritical.
+var EscalationThreshold = 100
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityInfo:
+		return "info"
+	case SeverityWarning:
+		return "warning"
+	case SeverityError:
+		return "error"
+	case SeverityCritical:
+		return "critical"
+	default:
+		return "unknown"
+	}
+}
+
+// SetCategorySeverity overrides the default severity of the category returned by
+// Category.Severity. A zero Severity removes the override. It is safe for concurrent use.
+func SetCategorySeverity(c Category, s Severity) {
+	severityMu.Lock()
+	defer severityMu.Unlock()
+	if s == 0 {
+		delete(severityOverrides, c)
+		return
+	}
+	severityOverrides[c] = s
+}
+
+// Severity returns the default severity of the category, unless overridden with SetCategorySeverity.
+// Client errors are warnings, server errors are errors and security errors are critical.
+func (c Category) Severity() Severity {
+	severityMu.RLock()
+	s, ok := severityOverrides[c]
+	severityMu.RUnlock()
+	if ok {
+		return s
+	}
+
+	switch c {
+	case ErrSecurity:
+		return SeverityCritical
+	case ErrInternal, ErrTimeout:
+		return SeverityError
+	case ErrCanceled:
+		return SeverityInfo
+	default:
+		if c.HTTPStatus() >= 500 {
+			return SeverityError
+		}
+		return SeverityWarning
+	}
+}
+
+// WithSeverity sets the severity of the AppError, overriding the category default.
+func (err AppError) WithSeverity(s Severity) *AppError {
+	e := err.clone()
+	e.Severity = s
+	return e
+}
+
+// EffectiveSeverity returns the Severity field if set, otherwise the default severity of the error's category.
+func (err AppError) EffectiveSeverity() Severity {
+	if err.Severity != 0 {
+		return err.Severity
+	}
+	return err.Code.Category.Severity()
+}
+
+// Escalate returns a copy of err raised to SeverityCritical when it has occurred at least
+// EscalationThreshold times, as reported by count. Otherwise err is returned unchanged.
+// It returns nil if err is nil.
+func Escalate(err *AppError, count int) *AppError {
+	if err == nil || count < EscalationThreshold {
+		return err
+	}
+	return err.WithSeverity(SeverityCritical)
+}
+
+// FilterBySeverity returns the AppErrors of errs whose EffectiveSeverity is at least min,
+// e.g. to decide which collected errors to alert on. Errors that are not an AppError are skipped.
+func FilterBySeverity(errs []error, min Severity) []*AppError {
+	var filtered []*AppError
+	for _, err := range errs {
+		var appErr *AppError
+		if errors.As(err, &appErr) && appErr.EffectiveSeverity() >= min {
+			filtered = append(filtered, appErr)
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,95 @@
+package apperror
+
+import (
+	"fmt"
+	"net/http"
+	"runtime"
+	"strings"
+)
+
+// maxStackDepth is the maximum number of frames captured in a stack.
+const maxStackDepth = 32
+
+// StackFrameLimit is the maximum number of frames rendered by FormatStack.
+// A value <= 0 renders every captured frame.
+var StackFrameLimit = 16
+
+// Recovered creates a new AppError with the ErrInternal category and a status code of 500
+// (Internal Server Error) from a value recovered from a panic, capturing the current stack.
+// The Message field is set to a generic text so the panic value is not exposed to clients.
+func Recovered(v any) *AppError {
+	cause, ok := v.(error)
+	if !ok {
+		cause = fmt.Errorf("panic: %v", v)
+	}
+
+	return &AppError{
+		Err:    cause,
+		Status: http.StatusInternalServerError,
+		Code: Code{
+			Category: ErrInternal,
+		},
+		Message: http.StatusText(http.StatusInternalServerError),
+		Stack:   callers(3),
+	}
+}
+
+// WithStack captures the current call stack into the AppError.
+func (err AppError) WithStack() *AppError {
+	e := err.clone()
+	e.Stack = callers(3)
+	return e
+}
+
+// callers returns the program counters of the calling stack, skipping skip frames.
+func callers(skip int) []uintptr {
+	pcs := make([]uintptr, maxStackDepth)
+	n := runtime.Callers(skip, pcs)
+	return pcs[:n]
+}
+
+// formatStack renders program counters as one "function file:line" entry per line.
+func formatStack(pcs []uintptr) string {
+	var b strings.Builder
+	frames := runtime.CallersFrames(pcs)
+	for {
+		frame, more := frames.Next()
+		fmt.Fprintf(&b, "%s %s:%d\n", frame.Function, frame.File, frame.Line)
+		if !more {
+			break
+		}
+	}
+	return b.String()
+}
+
+// FormatStack renders the captured stack as one "file:line function" entry per line,
+// innermost call first, skipping frames of the runtime and of internal packages.
+// At most StackFrameLimit frames are rendered. It returns "" when no stack was captured.
+func (err AppError) FormatStack() string {
+	if len(err.Stack) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	n := 0
+	frames := runtime.CallersFrames(err.Stack)
+	for {
+		frame, more := frames.Next()
+		if !isRuntimeFrame(frame.Function) {
+			if StackFrameLimit > 0 && n == StackFrameLimit {
+				break
+			}
+			fmt.Fprintf(&b, "%s:%d %s\n", frame.File, frame.Line, frame.Function)
+			n++
+		}
+		if !more {
+			break
+		}
+	}
+	return b.String()
+}
+
+// isRuntimeFrame reports whether function belongs to the runtime or an internal package of the standard library.
+func isRuntimeFrame(function string) bool {
+	return strings.HasPrefix(function, "runtime.") || strings.HasPrefix(function, "internal/")
+}
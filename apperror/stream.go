@@ -0,0 +1,52 @@
+package apperror
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// maxFrameSize bounds the size of a frame read by ReadFrom.
+const maxFrameSize = 1 << 20
+
+// WriteTo implements the io.WriterTo interface for AppError.
+// The error is written as a frame holding a 4-byte big-endian length followed by its JSON encoding,
+// which always keeps the internal category name regardless of Mode.
+func (err AppError) WriteTo(w io.Writer) (int64, error) {
+	body, marshalErr := json.Marshal(err.toJSON())
+	if marshalErr != nil {
+		return 0, marshalErr
+	}
+
+	frame := make([]byte, 4+len(body))
+	binary.BigEndian.PutUint32(frame, uint32(len(body)))
+	copy(frame[4:], body)
+
+	n, writeErr := w.Write(frame)
+	return int64(n), writeErr
+}
+
+// ReadFrom implements the io.ReaderFrom interface for AppError.
+// It reads a single frame written by WriteTo, waiting for partial reads to complete.
+func (err *AppError) ReadFrom(r io.Reader) (int64, error) {
+	var header [4]byte
+	n, readErr := io.ReadFull(r, header[:])
+	if readErr != nil {
+		return int64(n), readErr
+	}
+
+	size := binary.BigEndian.Uint32(header[:])
+	if size > maxFrameSize {
+		return int64(n), fmt.Errorf("apperror: frame of %d bytes exceeds the %d bytes limit", size, maxFrameSize)
+	}
+
+	body := make([]byte, size)
+	m, readErr := io.ReadFull(r, body)
+	total := int64(n + m)
+	if readErr != nil {
+		return total, readErr
+	}
+
+	return total, json.Unmarshal(body, err)
+}
@@ -0,0 +1,34 @@
+package apperror
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// Trailer names written by WriteTrailer.
+const (
+	TrailerCategory = "X-Error-Category"
+	TrailerStatus   = "X-Error-Status"
+	TrailerMessage  = "X-Error-Message"
+)
+
+// DeclareTrailers announces the error trailers in the Trailer header.
+// It must be called before the response headers are written, i.e. before the first
+// call to Write or WriteHeader, otherwise the trailers set by WriteTrailer are dropped.
+func DeclareTrailers(w http.ResponseWriter) {
+	h := w.Header()
+	h.Add("Trailer", TrailerCategory)
+	h.Add("Trailer", TrailerStatus)
+	h.Add("Trailer", TrailerMessage)
+}
+
+// WriteTrailer writes the error category, HTTP status and message into the
+// trailers declared with DeclareTrailers, so clients of a streaming response can detect
+// a failure that happened after the headers were sent. Like MarshalJSON, it uses the public
+// category name and SafeMessage unless Mode is ModeDevelopment.
+func (err AppError) WriteTrailer(w http.ResponseWriter) {
+	h := w.Header()
+	h.Set(TrailerCategory, err.Code.Category.renderedName())
+	h.Set(TrailerStatus, strconv.Itoa(err.HTTPStatus()))
+	h.Set(TrailerMessage, err.SafeMessage())
+}
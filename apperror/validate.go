@@ -0,0 +1,40 @@
+package apperror
+
+import "fmt"
+
+// requiredMetadata lists the metadata keys each category must carry.
+var requiredMetadata = map[Category][]string{
+	ErrValidation: {"field"},
+	ErrNotFound:   {"resource"},
+}
+
+// Validate reports whether the AppError carries the metadata its category requires:
+// a "field" for ErrValidation and a "resource" for ErrNotFound. It is meant to be
+// called in tests and debug builds to catch incomplete errors. A multi-error without
+// the key is valid when every one of its sub-errors carries it, as the errors built
+// by ValidationBuilder and FromProtoValidate do.
+func (err AppError) Validate() error {
+	for _, key := range requiredMetadata[err.Code.Category] {
+		if !err.hasMetadata(key) {
+			return fmt.Errorf("apperror: %s requires a non-empty %q metadata", err.Code.Category, key)
+		}
+	}
+	return nil
+}
+
+// hasMetadata reports whether the error, or else every one of its sub-errors, carries
+// a non-empty metadata under key.
+func (err AppError) hasMetadata(key string) bool {
+	if err.Metadata[key] != "" {
+		return true
+	}
+	if len(err.Errors) == 0 {
+		return false
+	}
+	for _, sub := range err.Errors {
+		if sub == nil || !sub.hasMetadata(key) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,28 @@
+package apperror
+
+// ErrorView is a flat representation of an AppError intended for templates,
+// so that fields can be accessed directly, e.g. {{.Message}} or {{.Field}}.
+type ErrorView struct {
+	Title     string
+	Message   string
+	Status    int
+	Code      string
+	Field     string
+	Info      string
+	RequestID string
+}
+
+// View returns an ErrorView populated from the AppError's category, code and metadata.
+// Like MarshalJSON, the title is the public category name and the message the SafeMessage
+// unless Mode is ModeDevelopment.
+func (err AppError) View() ErrorView {
+	return ErrorView{
+		Title:     err.Code.Category.renderedName(),
+		Message:   err.SafeMessage(),
+		Status:    err.HTTPStatus(),
+		Code:      err.Code.String(),
+		Field:     err.Field(),
+		Info:      err.Info(),
+		RequestID: err.RequestID(),
+	}
+}
@@ -0,0 +1,58 @@
+// Package zaperr converts AppErrors into typed zap fields.
+package zaperr
+
+import (
+	"maps"
+	"slices"
+
+	"github.com/ckminhano/golib/apperror"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Fields converts the AppError's category, HTTP status, code, message and metadata into zap fields.
+func Fields(err *apperror.AppError) []zap.Field {
+	fields := []zap.Field{
+		zap.String("category", err.Code.Category.String()),
+		zap.Int("status", err.HTTPStatus()),
+		zap.Int("code", int(err.Code.Internal)),
+	}
+	if err.Code.Service != "" {
+		fields = append(fields, zap.String("service", err.Code.Service))
+	}
+	if err.Err != nil {
+		fields = append(fields, zap.String("error", err.Err.Error()))
+	}
+	if err.Message != "" {
+		fields = append(fields, zap.String("message", err.Message))
+	}
+	if len(err.Metadata) > 0 {
+		fields = append(fields, zap.Object("metadata", metadata(err.Metadata)))
+	}
+	return fields
+}
+
+// Error returns a single zap field with key "error" nesting the fields returned by Fields.
+func Error(err *apperror.AppError) zap.Field {
+	return zap.Object("error", appError{err})
+}
+
+type appError struct {
+	err *apperror.AppError
+}
+
+func (e appError) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	for _, field := range Fields(e.err) {
+		field.AddTo(enc)
+	}
+	return nil
+}
+
+type metadata map[string]string
+
+func (m metadata) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	for _, k := range slices.Sorted(maps.Keys(m)) {
+		enc.AddString(k, m[k])
+	}
+	return nil
+}
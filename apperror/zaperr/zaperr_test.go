@@ -0,0 +1,51 @@
+package zaperr_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/ckminhano/golib/apperror"
+	"github.com/ckminhano/golib/apperror/zaperr"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestFields(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	logger := zap.New(core)
+
+	err := apperror.Timeout(errors.New("upstream slow")).WithField("user_id").WithService("billing")
+	logger.Error("request failed", zaperr.Fields(err)...)
+
+	fields := logs.All()[0].ContextMap()
+	want := map[string]any{
+		"category": "TimeoutError",
+		"status":   int64(504),
+		"service":  "billing",
+		"error":    "upstream slow",
+		"message":  "upstream slow",
+		"metadata": map[string]any{"field": "user_id"},
+	}
+	for k, v := range want {
+		if got := fields[k]; !reflect.DeepEqual(got, v) {
+			t.Errorf("field %q = %#v, want %#v", k, got, v)
+		}
+	}
+}
+
+func TestError(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	logger := zap.New(core)
+
+	err := apperror.NewAppError(errors.New("boom"), apperror.ErrInternal, nil)
+	logger.Error("request failed", zaperr.Error(err))
+
+	nested, ok := logs.All()[0].ContextMap()["error"].(map[string]any)
+	if !ok {
+		t.Fatalf("expected a nested error object, got %#v", logs.All()[0].ContextMap())
+	}
+	if nested["category"] != "InternalError" || nested["error"] != "boom" || nested["status"] != int64(500) {
+		t.Errorf("nested fields = %#v", nested)
+	}
+}
@@ -2,4 +2,12 @@ module github.com/ckminhano/golib
 
 go 1.24.4
 
-require github.com/google/uuid v1.6.0
+require (
+	connectrpc.com/connect v1.19.2
+	github.com/google/go-cmp v0.7.0
+	github.com/google/uuid v1.6.0
+	go.uber.org/zap v1.28.0
+	google.golang.org/protobuf v1.36.9
+)
+
+require go.uber.org/multierr v1.10.0 // indirect
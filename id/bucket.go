@@ -0,0 +1,17 @@
+package id
+
+import "hash/fnv"
+
+// Bucket returns a stable bucket index in [0, n) derived from the FNV-1a hash of the Id bytes,
+// so the same Id always maps to the same shard. It returns 0 if n <= 0.
+// It is safe to call on a nil Id, which is hashed as uuid.Nil.
+func (id *Id) Bucket(n int) int {
+	if n <= 0 {
+		return 0
+	}
+
+	h := fnv.New64a()
+	u := id.ToUUID()
+	h.Write(u[:])
+	return int(h.Sum64() % uint64(n))
+}
@@ -0,0 +1,33 @@
+package id
+
+import "context"
+
+// Source produces Ids, e.g. a SequentialGenerator.
+type Source interface {
+	Next() *Id
+}
+
+// SourceFunc adapts an ordinary function to a Source.
+type SourceFunc func() *Id
+
+// Next returns f().
+func (f SourceFunc) Next() *Id {
+	return f()
+}
+
+type generatorKey struct{}
+
+// ContextWithGenerator returns a copy of ctx carrying the Source used by NewIdFromContext.
+// It lets parallel tests use their own seeded generator without changing global state.
+func ContextWithGenerator(ctx context.Context, source Source) context.Context {
+	return context.WithValue(ctx, generatorKey{}, source)
+}
+
+// NewIdFromContext returns the next Id of the Source carried by ctx, set with
+// ContextWithGenerator, or a new random Id from NewId if there is none.
+func NewIdFromContext(ctx context.Context) *Id {
+	if source, ok := ctx.Value(generatorKey{}).(Source); ok && source != nil {
+		return source.Next()
+	}
+	return NewId()
+}
@@ -0,0 +1,58 @@
+package id
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// BracedIDs makes MarshalText and MarshalJSON emit the braced form {xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx}
+// required by some downstream systems. It defaults to false. Unmarshaling accepts both forms regardless.
+var BracedIDs = false
+
+// MarshalText implements the encoding.TextMarshaler interface.
+func (id Id) MarshalText() ([]byte, error) {
+	return []byte(id.text()), nil
+}
+
+// text returns the canonical form of the Id, braced if BracedIDs is set.
+func (id Id) text() string {
+	if BracedIDs {
+		return "{" + id.ToString() + "}"
+	}
+	return id.ToString()
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface using FromString,
+// which accepts the plain and braced forms.
+func (id *Id) UnmarshalText(text []byte) error {
+	parsed, err := FromString(string(text))
+	if err != nil {
+		return err
+	}
+	*id = *parsed
+	return nil
+}
+
+// MarshalJSON implements the json.Marshaler interface, encoding the Id as a JSON string.
+func (id Id) MarshalJSON() ([]byte, error) {
+	return json.Marshal(id.text())
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+// It accepts a JSON string parsed with FromString and rejects null.
+func (id *Id) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return errors.New("id: cannot unmarshal null into Id")
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	return id.UnmarshalText([]byte(s))
+}
+
+// Decode parses value into the Id like UnmarshalText. It is the fallback decoder
+// interface used by envconfig-style configuration libraries.
+func (id *Id) Decode(value string) error {
+	return id.UnmarshalText([]byte(value))
+}
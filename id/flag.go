@@ -0,0 +1,17 @@
+package id
+
+// String implements the fmt.Stringer and flag.Value interfaces. It is equivalent to ToString.
+func (id *Id) String() string {
+	return id.ToString()
+}
+
+// Set implements the flag.Value interface, so an Id can be used with flag.Var.
+// It parses s with FromString, rejecting invalid and nil UUIDs.
+func (id *Id) Set(s string) error {
+	parsed, err := FromString(s)
+	if err != nil {
+		return err
+	}
+	*id = *parsed
+	return nil
+}
@@ -0,0 +1,43 @@
+package id
+
+import "context"
+
+// Generator produces Ids from a Source ahead of consumption, buffering up to a fixed
+// number of them, e.g. to hand Ids out to workers.
+type Generator struct {
+	source Source
+	buffer int
+}
+
+// randomSource is the Source of random Ids used when none is provided.
+type randomSource struct{}
+
+func (randomSource) Next() *Id {
+	return NewId()
+}
+
+// NewGenerator creates a Generator drawing Ids from source, or random Ids from NewId if
+// source is nil, and buffering at most buffer of them.
+func NewGenerator(source Source, buffer int) *Generator {
+	if source == nil {
+		source = randomSource{}
+	}
+	return &Generator{source: source, buffer: max(0, buffer)}
+}
+
+// Stream returns a channel emitting Ids until ctx is canceled, after which the channel
+// is closed. Ids already buffered when ctx is canceled may still be received.
+func (g *Generator) Stream(ctx context.Context) <-chan *Id {
+	ch := make(chan *Id, g.buffer)
+	go func() {
+		defer close(ch)
+		for {
+			select {
+			case ch <- g.source.Next():
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch
+}
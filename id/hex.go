@@ -0,0 +1,32 @@
+package id
+
+import (
+	"encoding/hex"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// hexLen is the length of the unhyphenated hex form of a UUID.
+const hexLen = 32
+
+// Hex returns the Id as 32 lowercase hex characters without hyphens.
+// It is safe to call on a nil Id, which returns the hex form of the nil UUID.
+func (id *Id) Hex() string {
+	u := id.ToUUID()
+	return hex.EncodeToString(u[:])
+}
+
+// FromHex converts the 32 hex characters returned by Hex to an Id.
+// It rejects any other length, including the hyphenated form, and applies the same
+// nil UUID and version policies as FromString.
+func FromHex(s string) (*Id, error) {
+	if len(s) != hexLen {
+		return nil, fmt.Errorf("invalid id hex %q: expected %d hex characters", s, hexLen)
+	}
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid id hex %q: %w", s, err)
+	}
+	return fromParsed(uuid.UUID(b))
+}
@@ -2,39 +2,143 @@ package id
 
 import (
 	"errors"
+	"fmt"
+	"slices"
 
 	"github.com/google/uuid"
 )
 
+// ErrNilUUID is returned by every parsing entry point when the input is the nil UUID.
+// The nil UUID is never a valid domain id.
+var ErrNilUUID = errors.New("id: the nil UUID is not a valid id")
+
+// AllowedVersions restricts the UUID versions accepted by FromString.
+// An empty slice, the default, accepts every version.
+var AllowedVersions []int
+
 // Id is a wrapper around uuid.UUID to provide a custom type for IDs.
 // It is used to represent unique identifiers in the system.
-// The zero value of Id is a valid ID, representing a nil UUID.
+// The zero value of Id represents the nil UUID, which is never accepted as input:
+// FromString, FromBytes, UnmarshalText, UnmarshalJSON and Scan reject it with ErrNilUUID.
 type Id uuid.UUID
 
 // NewId creates a new Id with a random UUID.
+// It panics if the random number generator fails; use NewIdSafe to handle the error instead.
 func NewId() *Id {
 	id := Id(uuid.New())
 	return &id
 }
 
+// NewIdSafe creates a new Id with a random UUID like NewId, but returns an error instead
+// of panicking if the random number generator fails, so long-running servers can degrade gracefully.
+func NewIdSafe() (*Id, error) {
+	u, err := uuid.NewRandom()
+	if err != nil {
+		return nil, fmt.Errorf("id: generate random id: %w", err)
+	}
+	id := Id(u)
+	return &id, nil
+}
+
 // ToString converts the Id to a string representation of the UUID.
+// It uses the canonical lowercase form, so sorting the strings of v7 ids matches their creation order.
+// It is safe to call on a nil Id, which returns the empty string.
 func (id *Id) ToString() string {
+	if id == nil {
+		return ""
+	}
 	return uuid.UUID(*id).String()
 }
 
+// Masked returns the Id with every character except the last four hex digits replaced by '*',
+// keeping the canonical hyphenated shape, e.g. "********-****-****-****-********abcd".
+// It is safe to call on a nil Id, which returns the empty string.
+func (id *Id) Masked() string {
+	if id == nil {
+		return ""
+	}
+	s := id.ToString()
+	return "********-****-****-****-********" + s[len(s)-4:]
+}
+
 // ToUUID converts the Id to a uuid.UUID.
+// It is safe to call on a nil Id, which returns uuid.Nil.
 func (id *Id) ToUUID() uuid.UUID {
+	if id == nil {
+		return uuid.Nil
+	}
 	return uuid.UUID(*id)
 }
 
+// Version returns the UUID version of the Id (1, 4, 5, 7, etc.) read from the version nibble.
+// It is safe to call on a nil Id, which returns 0.
+func (id *Id) Version() int {
+	return int(id.ToUUID().Version())
+}
+
+// Variant returns the UUID variant of the Id.
+// It is safe to call on a nil Id, which returns the variant of uuid.Nil.
+func (id *Id) Variant() uuid.Variant {
+	return id.ToUUID().Variant()
+}
+
+// EqualUUID reports whether the Id holds the UUID u.
+// A nil Id is only equal to uuid.Nil.
+func (id *Id) EqualUUID(u uuid.UUID) bool {
+	return id.ToUUID() == u
+}
+
+// FromUUID converts a uuid.UUID to an Id.
+// It returns nil if u is the nil UUID, as the nil UUID is not a valid id; use FromUUIDAllowNil to accept it.
+func FromUUID(u uuid.UUID) *Id {
+	if u == uuid.Nil {
+		return nil
+	}
+	return FromUUIDAllowNil(u)
+}
+
+// FromUUIDAllowNil converts a uuid.UUID to an Id, accepting the nil UUID.
+func FromUUIDAllowNil(u uuid.UUID) *Id {
+	id := Id(u)
+	return &id
+}
+
 /*
 FromString converts a string representation of a UUID to an Id.
-It returns an error if the string is empty or s is a nil UUID in the form 0000000-0000-0000-0000-000000000000.
+It returns an error if the string is empty or is not a valid UUID, and ErrNilUUID if s is a nil UUID
+in the form 0000000-0000-0000-0000-000000000000.
+It also returns an error if AllowedVersions is set and does not contain the version of s.
 */
 func FromString(s string) (*Id, error) {
-	if s == "" || s == uuid.Nil.String() {
+	if s == "" {
 		return nil, errors.New("string s cannot be empty")
 	}
-	id := Id(uuid.MustParse(s))
+	u, err := uuid.Parse(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid id %q: %w", s, err)
+	}
+	return fromParsed(u)
+}
+
+// FromBytes converts the 16 bytes of a UUID to an Id.
+// It returns an error if b is not 16 bytes long, and ErrNilUUID if b is the nil UUID.
+// The AllowedVersions policy applies as in FromString.
+func FromBytes(b []byte) (*Id, error) {
+	u, err := uuid.FromBytes(b)
+	if err != nil {
+		return nil, fmt.Errorf("invalid id bytes: %w", err)
+	}
+	return fromParsed(u)
+}
+
+// fromParsed applies the nil UUID and AllowedVersions policies to a parsed UUID.
+func fromParsed(u uuid.UUID) (*Id, error) {
+	if u == uuid.Nil {
+		return nil, ErrNilUUID
+	}
+	id := Id(u)
+	if len(AllowedVersions) > 0 && !slices.Contains(AllowedVersions, id.Version()) {
+		return nil, fmt.Errorf("id %q has version %d, allowed versions are %v", u, id.Version(), AllowedVersions)
+	}
 	return &id, nil
 }
@@ -1,8 +1,23 @@
 package id_test
 
 import (
+	"context"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"flag"
+	"io"
+	"net/http"
 	"os"
+	"slices"
+	"sort"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/ckminhano/golib/apperror"
+	"github.com/ckminhano/golib/id"
+	"github.com/google/uuid"
 )
 
 func TestMain(m *testing.M) {
@@ -10,4 +25,806 @@ func TestMain(m *testing.M) {
 	os.Exit(code)
 }
 
-// TODO: Implement unit tests
+func TestToStrings(t *testing.T) {
+	a, b := id.NewId(), id.NewId()
+
+	got := id.ToStrings([]*id.Id{a, nil, b})
+	want := []string{a.ToString(), b.ToString()}
+
+	if len(got) != len(want) {
+		t.Fatalf("len = %d, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("index %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestFromStrings(t *testing.T) {
+	a, b := id.NewId(), id.NewId()
+
+	ids, err := id.FromStrings([]string{a.ToString(), b.ToString()})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if *ids[0] != *a || *ids[1] != *b {
+		t.Errorf("FromStrings() = %v, want [%v %v]", id.ToStrings(ids), a.ToString(), b.ToString())
+	}
+
+	_, err = id.FromStrings([]string{a.ToString(), "not-an-id", b.ToString()})
+	if err == nil {
+		t.Fatal("expected an error for the invalid entry")
+	}
+	if !strings.Contains(err.Error(), "index 1") || !strings.Contains(err.Error(), "not-an-id") {
+		t.Errorf("error %q does not report the index and value", err)
+	}
+
+	if _, err := id.FromStrings([]string{a.ToString(), ""}); err == nil || !strings.Contains(err.Error(), "index 1") {
+		t.Errorf("FromStrings() of an empty entry = %v, want an error reporting index 1", err)
+	}
+}
+
+func TestStringsRoundTrip(t *testing.T) {
+	a, b := id.NewId(), id.NewId()
+
+	ids, err := id.FromStrings(id.ToStrings([]*id.Id{a, nil, b}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(ids) != 2 || *ids[0] != *a || *ids[1] != *b {
+		t.Errorf("round trip = %v, want [%v %v]", id.ToStrings(ids), a.ToString(), b.ToString())
+	}
+}
+
+func TestVersion(t *testing.T) {
+	v4 := id.NewId()
+	if got := v4.Version(); got != 4 {
+		t.Errorf("Version() = %d, want 4", got)
+	}
+	if got := v4.Variant(); got != uuid.RFC4122 {
+		t.Errorf("Variant() = %v, want %v", got, uuid.RFC4122)
+	}
+
+	v7 := id.Id(uuid.Must(uuid.NewV7()))
+	if got := v7.Version(); got != 7 {
+		t.Errorf("Version() = %d, want 7", got)
+	}
+
+	var nilID *id.Id
+	if got := nilID.Version(); got != 0 {
+		t.Errorf("nil Version() = %d, want 0", got)
+	}
+	if got := nilID.Variant(); got != uuid.Nil.Variant() {
+		t.Errorf("nil Variant() = %v, want %v", got, uuid.Nil.Variant())
+	}
+}
+
+func TestFromStringAllowedVersions(t *testing.T) {
+	t.Cleanup(func() { id.AllowedVersions = nil })
+
+	v1 := uuid.Must(uuid.NewUUID()).String()
+	v4 := uuid.New().String()
+	v7 := uuid.Must(uuid.NewV7()).String()
+
+	if _, err := id.FromString(v1); err != nil {
+		t.Fatalf("default should accept v1, got %v", err)
+	}
+
+	id.AllowedVersions = []int{4, 7}
+	for _, s := range []string{v4, v7} {
+		if _, err := id.FromString(s); err != nil {
+			t.Errorf("FromString(%q) unexpected error: %v", s, err)
+		}
+	}
+
+	_, err := id.FromString(v1)
+	if err == nil {
+		t.Fatal("expected v1 id to be rejected")
+	}
+	if !strings.Contains(err.Error(), "version 1") {
+		t.Errorf("error %q does not describe the rejected version", err)
+	}
+}
+
+func TestNilReceiver(t *testing.T) {
+	var nilId *id.Id
+
+	if got := nilId.ToString(); got != "" {
+		t.Errorf("ToString() = %q, want empty string", got)
+	}
+	if got := nilId.ToUUID(); got != uuid.Nil {
+		t.Errorf("ToUUID() = %v, want uuid.Nil", got)
+	}
+}
+
+func TestMasked(t *testing.T) {
+	i, err := id.FromString("6f1c2d3e-4b5a-4c6d-8e7f-0a1b2c3dabcd")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "********-****-****-****-********abcd"
+	if got := i.Masked(); got != want {
+		t.Errorf("Masked() = %q, want %q", got, want)
+	}
+	if got := len(i.Masked()); got != len(i.ToString()) {
+		t.Errorf("len(Masked()) = %d, want %d", got, len(i.ToString()))
+	}
+}
+
+func TestKey(t *testing.T) {
+	a := id.NewId()
+	b, err := id.FromString(a.ToString())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a == b {
+		t.Fatal("expected distinct pointers")
+	}
+
+	if a.Key() != b.Key() {
+		t.Errorf("Key() differs for the same UUID: %v vs %v", a.Key(), b.Key())
+	}
+
+	seen := map[id.Key]bool{a.Key(): true}
+	if !seen[b.Key()] {
+		t.Error("expected lookup by value to find the key")
+	}
+	if got := a.Key().Id(); *got != *a {
+		t.Errorf("Key().Id() = %s, want %s", got.ToString(), a.ToString())
+	}
+}
+
+func TestFromPathSegment(t *testing.T) {
+	want := id.NewId()
+
+	got, err := id.FromPathSegment(want.PathSegment())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if *got != *want {
+		t.Errorf("FromPathSegment() = %s, want %s", got.ToString(), want.ToString())
+	}
+
+	for _, s := range []string{
+		"{" + want.ToString() + "}",
+		"urn:uuid:" + want.ToString(),
+		strings.ReplaceAll(want.ToString(), "-", ""),
+	} {
+		if _, err := id.FromPathSegment(s); err == nil {
+			t.Errorf("FromPathSegment(%q) expected an error", s)
+		}
+	}
+}
+
+func TestBucket(t *testing.T) {
+	i := id.NewId()
+	if i.Bucket(16) != i.Bucket(16) {
+		t.Error("expected Bucket to be deterministic")
+	}
+	if got := i.Bucket(0); got != 0 {
+		t.Errorf("Bucket(0) = %d, want 0", got)
+	}
+	if got := i.Bucket(-1); got != 0 {
+		t.Errorf("Bucket(-1) = %d, want 0", got)
+	}
+	var nilID *id.Id
+	zero := id.Id(uuid.Nil)
+	if got, want := nilID.Bucket(16), zero.Bucket(16); got != want {
+		t.Errorf("nil Bucket(16) = %d, want %d", got, want)
+	}
+
+	const buckets, samples = 8, 8000
+	counts := make([]int, buckets)
+	for range samples {
+		b := id.NewId().Bucket(buckets)
+		if b < 0 || b >= buckets {
+			t.Fatalf("Bucket() = %d, out of range", b)
+		}
+		counts[b]++
+	}
+
+	expected := samples / buckets
+	for b, c := range counts {
+		if c < expected*3/4 || c > expected*5/4 {
+			t.Errorf("bucket %d has %d ids, want about %d", b, c, expected)
+		}
+	}
+}
+
+func TestSequentialGenerator(t *testing.T) {
+	g := id.NewSequentialGenerator(0)
+
+	for _, want := range []string{
+		"00000000-0000-0000-0000-000000000001",
+		"00000000-0000-0000-0000-000000000002",
+		"00000000-0000-0000-0000-000000000003",
+	} {
+		if got := g.Next().ToString(); got != want {
+			t.Errorf("Next() = %s, want %s", got, want)
+		}
+	}
+
+	if got, want := id.NewSequentialGenerator(9).Next().ToString(), "00000000-0000-0000-0000-00000000000a"; got != want {
+		t.Errorf("Next() with seed = %s, want %s", got, want)
+	}
+}
+
+func TestToStringV7Ordering(t *testing.T) {
+	var created []string
+	for range 5 {
+		v7 := id.Id(uuid.Must(uuid.NewV7()))
+		created = append(created, v7.ToString())
+		time.Sleep(time.Millisecond)
+	}
+
+	sorted := slices.Clone(created)
+	sort.Strings(sorted)
+	if !slices.Equal(sorted, created) {
+		t.Errorf("sorted %v, want creation order %v", sorted, created)
+	}
+}
+
+func TestFromUUID(t *testing.T) {
+	u := uuid.New()
+
+	got := id.FromUUID(u)
+	if got == nil || !got.EqualUUID(u) {
+		t.Fatalf("FromUUID(%s) = %v, want an equal id", u, got)
+	}
+	if got.EqualUUID(uuid.New()) {
+		t.Error("expected a different UUID not to be equal")
+	}
+
+	if got := id.FromUUID(uuid.Nil); got != nil {
+		t.Errorf("FromUUID(uuid.Nil) = %s, want nil", got.ToString())
+	}
+	if got := id.FromUUIDAllowNil(uuid.Nil); got == nil || !got.EqualUUID(uuid.Nil) {
+		t.Errorf("FromUUIDAllowNil(uuid.Nil) = %v, want the nil UUID", got)
+	}
+
+	var nilId *id.Id
+	if !nilId.EqualUUID(uuid.Nil) || nilId.EqualUUID(u) {
+		t.Error("expected a nil id to only equal uuid.Nil")
+	}
+}
+
+func TestFlagValue(t *testing.T) {
+	want := id.NewId()
+
+	var got id.Id
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.Var(&got, "id", "the id")
+
+	if err := fs.Parse([]string{"-id", want.ToString()}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != *want {
+		t.Errorf("parsed %s, want %s", got.String(), want.String())
+	}
+
+	for _, arg := range []string{"not-an-id", uuid.Nil.String()} {
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		fs.SetOutput(io.Discard)
+		fs.Var(&got, "id", "the id")
+		if err := fs.Parse([]string{"-id", arg}); err == nil {
+			t.Errorf("Parse(%q) expected an error", arg)
+		}
+	}
+}
+
+func TestNilUUIDPolicy(t *testing.T) {
+	nilString := uuid.Nil.String()
+	var target id.Id
+
+	entryPoints := map[string]func() error{
+		"FromString": func() error {
+			_, err := id.FromString(nilString)
+			return err
+		},
+		"FromBytes": func() error {
+			_, err := id.FromBytes(uuid.Nil[:])
+			return err
+		},
+		"UnmarshalText": func() error {
+			return target.UnmarshalText([]byte(nilString))
+		},
+		"UnmarshalJSON": func() error {
+			return json.Unmarshal([]byte(`"`+nilString+`"`), &target)
+		},
+		"Scan string": func() error {
+			return target.Scan(nilString)
+		},
+		"Scan bytes": func() error {
+			return target.Scan(uuid.Nil[:])
+		},
+	}
+
+	for name, parse := range entryPoints {
+		if err := parse(); !errors.Is(err, id.ErrNilUUID) {
+			t.Errorf("%s error = %v, want %v", name, err, id.ErrNilUUID)
+		}
+	}
+}
+
+func TestEncodingRoundTrip(t *testing.T) {
+	want := id.NewId()
+
+	data, err := json.Marshal(struct {
+		ID *id.Id `json:"id"`
+	}{want})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"id":"`+want.ToString()+`"}` {
+		t.Errorf("JSON = %s", data)
+	}
+
+	var decoded struct {
+		ID id.Id `json:"id"`
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.ID != *want {
+		t.Errorf("decoded %s, want %s", decoded.ID.String(), want)
+	}
+	if err := json.Unmarshal([]byte(`{"id":null}`), &decoded); err == nil {
+		t.Error("expected null to be rejected")
+	}
+
+	fromBytes, err := id.FromBytes(want[:])
+	if err != nil || *fromBytes != *want {
+		t.Errorf("FromBytes() = %v, %v, want %s", fromBytes, err, want)
+	}
+
+	value, err := want.Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var scanned id.Id
+	if err := scanned.Scan(value); err != nil || scanned != *want {
+		t.Errorf("Scan(Value()) = %s, %v, want %s", scanned.String(), err, want)
+	}
+	if err := scanned.Scan(nil); err == nil {
+		t.Error("expected NULL to be rejected")
+	}
+}
+
+func TestCreatedBetween(t *testing.T) {
+	before := time.Now().Add(-time.Second)
+	v7 := id.FromUUID(uuid.Must(uuid.NewV7()))
+	after := time.Now().Add(time.Second)
+
+	created, ok := v7.Time()
+	if !ok || created.Before(before) || !created.Before(after) {
+		t.Fatalf("Time() = %v, %v, want a time between %v and %v", created, ok, before, after)
+	}
+	if !v7.CreatedBetween(before, after) {
+		t.Error("expected the id to be inside the window")
+	}
+	if v7.CreatedBetween(after, after.Add(time.Hour)) {
+		t.Error("expected the id to be outside a later window")
+	}
+	if v7.CreatedBetween(before.Add(-time.Hour), before) {
+		t.Error("expected the id to be outside an earlier window")
+	}
+	if id.NewId().CreatedBetween(before, after) {
+		t.Error("expected a v4 id never to be inside a window")
+	}
+}
+
+var _ encoding.TextUnmarshaler = (*id.Id)(nil)
+
+func TestDecode(t *testing.T) {
+	want := id.NewId()
+
+	var got id.Id
+	if err := got.Decode(want.ToString()); err != nil {
+		t.Fatalf("Decode() unexpected error: %v", err)
+	}
+	if got != *want {
+		t.Errorf("Decode() = %s, want %s", got.String(), want)
+	}
+
+	for _, value := range []string{"", "not-an-id", uuid.Nil.String()} {
+		if err := got.Decode(value); err == nil {
+			t.Errorf("Decode(%q) expected an error", value)
+		}
+	}
+}
+
+func TestIdempotencyKey(t *testing.T) {
+	a := id.IdempotencyKey("POST", "/orders", "customer-1")
+	b := id.IdempotencyKey("POST", "/orders", "customer-1")
+	if *a != *b {
+		t.Errorf("IdempotencyKey() is not deterministic: %s vs %s", a, b)
+	}
+	if got := a.Version(); got != 5 {
+		t.Errorf("Version() = %d, want 5", got)
+	}
+
+	for _, parts := range [][]string{
+		{"POST", "/orders", "customer-2"},
+		{"POST/", "orders", "customer-1"},
+	} {
+		if other := id.IdempotencyKey(parts...); *other == *a {
+			t.Errorf("IdempotencyKey(%q) collides with a different signature", parts)
+		}
+	}
+}
+
+func TestIdsSort(t *testing.T) {
+	want := id.Ids{nil, nil}
+	for _, s := range []string{
+		"00000000-0000-4000-8000-000000000001",
+		"0f000000-0000-4000-8000-000000000000",
+		"7a000000-0000-4000-8000-000000000000",
+		"ff000000-0000-4000-8000-000000000000",
+	} {
+		i, err := id.FromString(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want = append(want, i)
+	}
+
+	got := id.Ids{want[4], nil, want[2], want[5], nil, want[3]}
+	got.Sort()
+	if !slices.Equal(got, want) {
+		t.Errorf("Sort() = %v, want %v", id.ToStrings(got), id.ToStrings(want))
+	}
+
+	again := slices.Clone(got)
+	again.Sort()
+	if !slices.Equal(again, got) {
+		t.Error("sorting a sorted slice changed its order")
+	}
+}
+
+func TestMissing(t *testing.T) {
+	a, b, c := id.NewId(), id.NewId(), id.NewId()
+	copyOfB := id.FromUUID(b.ToUUID())
+
+	tests := []struct {
+		name       string
+		candidates []*id.Id
+		present    id.IdSet
+		want       []*id.Id
+	}{
+		{"overlapping", []*id.Id{a, b, c}, id.NewIdSet(copyOfB), []*id.Id{a, c}},
+		{"disjoint", []*id.Id{a, c}, id.NewIdSet(b), []*id.Id{a, c}},
+		{"all present", []*id.Id{a, b}, id.NewIdSet(a, b, c), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := id.Missing(tt.candidates, tt.present); !slices.Equal(got, tt.want) {
+				t.Errorf("Missing() = %v, want %v", id.ToStrings(got), id.ToStrings(tt.want))
+			}
+		})
+	}
+}
+
+func TestIntersect(t *testing.T) {
+	a, b, c := id.NewId(), id.NewId(), id.NewId()
+
+	got := id.Intersect(id.NewIdSet(a, b), id.NewIdSet(b, c))
+	if len(got) != 1 || !got.Contains(b) {
+		t.Errorf("Intersect() of overlapping sets = %v, want only %s", got, b.ToString())
+	}
+
+	if got := id.Intersect(id.NewIdSet(a), id.NewIdSet(b, c)); len(got) != 0 {
+		t.Errorf("Intersect() of disjoint sets = %v, want empty", got)
+	}
+}
+
+func TestScanIds(t *testing.T) {
+	a, b := id.NewId(), id.NewId()
+	input := a.ToString() + "\n\n  " + b.ToString() + "  \nnot-an-id\n"
+
+	ids, errs := id.ScanIds(strings.NewReader(input))
+	if want := []string{a.ToString(), b.ToString()}; !slices.Equal(id.ToStrings(ids), want) {
+		t.Errorf("ids = %v, want %v", id.ToStrings(ids), want)
+	}
+	if len(errs) != 1 {
+		t.Fatalf("errs = %v, want one error", errs)
+	}
+	if !strings.HasPrefix(errs[0].Error(), "line 4: ") {
+		t.Errorf("error = %q, want it to report line 4", errs[0])
+	}
+}
+
+func TestUniqueGenerator(t *testing.T) {
+	first, second := id.NewId(), id.NewId()
+	rigged := []*id.Id{first, first, second}
+	source := id.SourceFunc(func() *id.Id {
+		next := rigged[0]
+		rigged = rigged[1:]
+		return next
+	})
+
+	g := id.NewUniqueGenerator(source, 1)
+	if got, err := g.Next(); err != nil || got != first {
+		t.Fatalf("Next() = %v, %v, want %s", got, err, first.ToString())
+	}
+	if got, err := g.Next(); err != nil || got != second {
+		t.Fatalf("Next() after a collision = %v, %v, want %s", got, err, second.ToString())
+	}
+
+	same := id.NewUniqueGenerator(id.SourceFunc(func() *id.Id { return first }), 2)
+	if _, err := same.Next(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := same.Next(); !errors.Is(err, id.ErrCollision) {
+		t.Errorf("Next() error = %v, want %v", err, id.ErrCollision)
+	}
+
+	sequential := id.NewUniqueGenerator(id.NewSequentialGenerator(1), 0)
+	if _, err := sequential.Next(); err != nil {
+		t.Errorf("Next() from a SequentialGenerator = %v", err)
+	}
+}
+
+func TestMatchOrError(t *testing.T) {
+	stored := id.NewId()
+
+	if err := id.MatchOrError(id.FromUUID(stored.ToUUID()), stored); err != nil {
+		t.Errorf("MatchOrError() of equal ids = %v, want nil", err)
+	}
+
+	incoming := id.NewId()
+	err := id.MatchOrError(incoming, stored)
+	if err == nil {
+		t.Fatal("MatchOrError() of different ids = nil, want an error")
+	}
+	if err.Code.Category != apperror.ErrConflict || err.HTTPStatus() != http.StatusConflict {
+		t.Errorf("category = %v, status = %d, want a conflict", err.Code.Category, err.HTTPStatus())
+	}
+	if err.Metadata["have"] != incoming.ToString() || err.Metadata["want"] != stored.ToString() {
+		t.Errorf("metadata = %v, want both ids", err.Metadata)
+	}
+}
+
+func TestUint128RoundTrip(t *testing.T) {
+	original := id.NewId()
+
+	got := id.FromUint128(original.High64(), original.Low64())
+	if got.ToString() != original.ToString() {
+		t.Errorf("FromUint128() = %s, want %s", got.ToString(), original.ToString())
+	}
+
+	i := id.FromUint128(0x0123456789abcdef, 0xfedcba9876543210)
+	if want := "01234567-89ab-cdef-fedc-ba9876543210"; i.ToString() != want {
+		t.Errorf("FromUint128() = %s, want %s", i.ToString(), want)
+	}
+	if i.High64() != 0x0123456789abcdef || i.Low64() != 0xfedcba9876543210 {
+		t.Errorf("High64(), Low64() = %x, %x", i.High64(), i.Low64())
+	}
+
+	var nilId *id.Id
+	if nilId.High64() != 0 || nilId.Low64() != 0 {
+		t.Error("halves of a nil Id should be 0")
+	}
+}
+
+func TestNewIdFromContext(t *testing.T) {
+	t.Parallel()
+
+	ctx := id.ContextWithGenerator(context.Background(), id.NewSequentialGenerator(41))
+	if got, want := id.NewIdFromContext(ctx).ToString(), "00000000-0000-0000-0000-00000000002a"; got != want {
+		t.Errorf("NewIdFromContext() with a generator = %s, want %s", got, want)
+	}
+	if got, want := id.NewIdFromContext(ctx).ToString(), "00000000-0000-0000-0000-00000000002b"; got != want {
+		t.Errorf("second NewIdFromContext() with a generator = %s, want %s", got, want)
+	}
+
+	random := id.NewIdFromContext(context.Background())
+	if random.Version() != 4 {
+		t.Errorf("NewIdFromContext() without a generator = %s, want a random v4 id", random.ToString())
+	}
+}
+
+func TestBracedIDs(t *testing.T) {
+	i, err := id.FromString("01234567-89ab-4def-8123-456789abcdef")
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain, braced := `"01234567-89ab-4def-8123-456789abcdef"`, `"{01234567-89ab-4def-8123-456789abcdef}"`
+
+	for _, tt := range []struct {
+		braced bool
+		want   string
+	}{{false, plain}, {true, braced}} {
+		id.BracedIDs = tt.braced
+		data, err := json.Marshal(i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != tt.want {
+			t.Errorf("BracedIDs = %v: MarshalJSON() = %s, want %s", tt.braced, data, tt.want)
+		}
+		text, _ := i.MarshalText()
+		if string(text) != strings.Trim(tt.want, `"`) {
+			t.Errorf("BracedIDs = %v: MarshalText() = %s", tt.braced, text)
+		}
+	}
+	id.BracedIDs = false
+
+	for _, data := range []string{plain, braced} {
+		var got id.Id
+		if err := json.Unmarshal([]byte(data), &got); err != nil {
+			t.Errorf("Unmarshal(%s) error = %v", data, err)
+			continue
+		}
+		if got != *i {
+			t.Errorf("Unmarshal(%s) = %s, want %s", data, got.ToString(), i.ToString())
+		}
+	}
+}
+
+// failingReader is a random source that always fails.
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) {
+	return 0, errors.New("entropy exhausted")
+}
+
+func TestNewIdSafe(t *testing.T) {
+	i, err := id.NewIdSafe()
+	if err != nil || i.Version() != 4 {
+		t.Fatalf("NewIdSafe() = %v, %v, want a random v4 id", i, err)
+	}
+
+	uuid.SetRand(failingReader{})
+	t.Cleanup(func() { uuid.SetRand(nil) })
+
+	i, err = id.NewIdSafe()
+	if err == nil || i != nil {
+		t.Errorf("NewIdSafe() with a failing reader = %v, %v, want an error", i, err)
+	}
+}
+
+func TestPlaceholders(t *testing.T) {
+	a, b := id.NewId(), id.NewId()
+
+	query, args := id.Placeholders([]*id.Id{a, b, nil}, 2)
+	if want := "($2),($3),($4)"; query != want {
+		t.Errorf("placeholders = %q, want %q", query, want)
+	}
+	if want := []any{a.ToString(), b.ToString(), nil}; !slices.Equal(args, want) {
+		t.Errorf("args = %v, want %v", args, want)
+	}
+
+	if query, args := id.Placeholders(nil, 1); query != "" || args != nil {
+		t.Errorf("Placeholders(nil) = %q, %v, want empty", query, args)
+	}
+}
+
+func TestHexRoundTrip(t *testing.T) {
+	original := id.NewId()
+
+	h := original.Hex()
+	if len(h) != 32 || h != strings.ReplaceAll(original.ToString(), "-", "") {
+		t.Errorf("Hex() = %q, want the unhyphenated form of %s", h, original.ToString())
+	}
+	got, err := id.FromHex(h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *got != *original {
+		t.Errorf("FromHex(Hex()) = %s, want %s", got.ToString(), original.ToString())
+	}
+	if upper, err := id.FromHex(strings.ToUpper(h)); err != nil || *upper != *original {
+		t.Errorf("FromHex(upper) = %v, %v", upper, err)
+	}
+
+	for _, s := range []string{"", h[:31], h + "0", original.ToString(), strings.Repeat("z", 32)} {
+		if _, err := id.FromHex(s); err == nil {
+			t.Errorf("FromHex(%q) succeeded, want an error", s)
+		}
+	}
+	if _, err := id.FromHex(strings.Repeat("0", 32)); !errors.Is(err, id.ErrNilUUID) {
+		t.Errorf("FromHex(nil UUID) error = %v, want %v", err, id.ErrNilUUID)
+	}
+}
+
+func TestGeneratorStream(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	ids := id.NewGenerator(nil, 8).Stream(ctx)
+
+	seen := id.NewIdSet()
+	for range 100 {
+		i := <-ids
+		if seen.Contains(i) {
+			t.Fatalf("duplicate id %s", i.ToString())
+		}
+		seen.Add(i)
+	}
+
+	cancel()
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case _, ok := <-ids:
+			if !ok {
+				return
+			}
+		case <-timeout:
+			t.Fatal("channel not closed after cancel")
+		}
+	}
+}
+
+func TestRequireIDs(t *testing.T) {
+	type request struct {
+		UserID  *id.Id
+		OrgID   id.Id
+		OwnerID *id.Id
+		Name    string
+	}
+
+	complete := request{UserID: id.NewId(), OrgID: *id.NewId(), OwnerID: id.NewId()}
+	if err := id.RequireIDs(complete, "UserID", "OrgID", "OwnerID"); err != nil {
+		t.Errorf("RequireIDs() of a complete struct = %v, want nil", err)
+	}
+
+	missing := request{UserID: id.NewId(), OwnerID: &id.Id{}}
+	err := id.RequireIDs(&missing, "UserID", "OrgID")
+	if err == nil {
+		t.Fatal("RequireIDs() = nil, want an error for OrgID")
+	}
+	if err.Code.Category != apperror.ErrValidation || len(err.Errors) != 1 || err.Errors[0].Field() != "OrgID" {
+		t.Errorf("RequireIDs() = %v, want a validation error for OrgID", err)
+	}
+
+	if err := id.RequireIDs(missing, "OwnerID"); err == nil || err.Errors[0].Field() != "OwnerID" {
+		t.Errorf("RequireIDs() of a nil UUID pointer = %v, want an error for OwnerID", err)
+	}
+
+	for name, call := range map[string]func(){
+		"not a struct":  func() { id.RequireIDs("x", "UserID") },
+		"unknown field": func() { id.RequireIDs(complete, "Missing") },
+		"wrong type":    func() { id.RequireIDs(complete, "Name") },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s: RequireIDs() did not panic", name)
+				}
+			}()
+			call()
+		}()
+	}
+}
+
+func TestFromUserInput(t *testing.T) {
+	want := "0f8fad5b-d9cb-469f-a165-70867728950e"
+
+	for _, input := range []string{
+		want,
+		"  " + want + "\n",
+		`"` + want + `"`,
+		"'" + want + "'.",
+		"[" + want + "]",
+		"(" + want + "),",
+		` ["{` + want + `}"] `,
+		"<urn:uuid:" + want + ">",
+	} {
+		got, err := id.FromUserInput(input)
+		if err != nil {
+			t.Errorf("FromUserInput(%q) error = %v", input, err)
+			continue
+		}
+		if got.ToString() != want {
+			t.Errorf("FromUserInput(%q) = %s, want %s", input, got.ToString(), want)
+		}
+	}
+
+	for _, input := range []string{"", `""`, `"` + want, "id: " + want, want + "x"} {
+		if _, err := id.FromUserInput(input); err == nil {
+			t.Errorf("FromUserInput(%q) succeeded, want an error", input)
+		}
+	}
+}
@@ -0,0 +1,21 @@
+package id
+
+import (
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// IdempotencyNamespace is the v5 UUID namespace used by IdempotencyKey.
+var IdempotencyNamespace = uuid.NewSHA1(uuid.NameSpaceURL, []byte("https://github.com/ckminhano/golib/id/idempotency"))
+
+// idempotencySeparator joins the parts of an idempotency key. A NUL byte keeps
+// ("ab", "c") and ("a", "bc") distinct.
+const idempotencySeparator = "\x00"
+
+// IdempotencyKey returns a deterministic v5 Id derived from the parts of a request signature,
+// so identical requests produce the same Id.
+func IdempotencyKey(parts ...string) *Id {
+	id := Id(uuid.NewSHA1(IdempotencyNamespace, []byte(strings.Join(parts, idempotencySeparator))))
+	return &id
+}
@@ -0,0 +1,64 @@
+// Package idtest provides helpers for tests that work with ids.
+package idtest
+
+import (
+	"testing"
+
+	"github.com/ckminhano/golib/id"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/uuid"
+)
+
+// MustNew returns a new random Id, failing the test if it is the nil UUID.
+func MustNew(t testing.TB) *id.Id {
+	t.Helper()
+	i := id.NewId()
+	if i.ToUUID() == uuid.Nil {
+		t.Fatalf("id.NewId() returned the nil UUID")
+	}
+	return i
+}
+
+// AssertEqual reports a test error if got and want do not hold the same UUID.
+// Two nil ids are equal.
+func AssertEqual(t testing.TB, got, want *id.Id) {
+	t.Helper()
+	if got == nil || want == nil {
+		if got != want {
+			t.Errorf("id = %s, want %s", format(got), format(want))
+		}
+		return
+	}
+	if *got != *want {
+		t.Errorf("id = %s, want %s", format(got), format(want))
+	}
+}
+
+// AssertNotZero reports a test error if i is nil or the nil UUID.
+func AssertNotZero(t testing.TB, i *id.Id) {
+	t.Helper()
+	if i.ToUUID() == uuid.Nil {
+		t.Errorf("id = %s, want a non-zero id", format(i))
+	}
+}
+
+// IDComparer returns a cmp.Option comparing ids by their UUID value, for use with
+// cmp.Diff and cmp.Equal on structs holding Id or *Id fields:
+//
+//	if diff := cmp.Diff(want, got, idtest.IDComparer()); diff != "" {
+//		t.Errorf("mismatch (-want +got):\n%s", diff)
+//	}
+//
+// Two nil *Id are equal, while a nil *Id differs from any non-nil one.
+func IDComparer() cmp.Option {
+	return cmp.Comparer(func(a, b id.Id) bool {
+		return a == b
+	})
+}
+
+func format(i *id.Id) string {
+	if i == nil {
+		return "<nil>"
+	}
+	return i.ToString()
+}
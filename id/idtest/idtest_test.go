@@ -0,0 +1,97 @@
+package idtest_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/ckminhano/golib/id"
+	"github.com/ckminhano/golib/id/idtest"
+	"github.com/google/go-cmp/cmp"
+)
+
+// fakeTB records failures instead of failing the running test.
+type fakeTB struct {
+	testing.TB
+	errors []string
+}
+
+func (f *fakeTB) Helper() {}
+
+func (f *fakeTB) Errorf(format string, args ...any) {
+	f.errors = append(f.errors, fmt.Sprintf(format, args...))
+}
+
+func (f *fakeTB) Fatalf(format string, args ...any) {
+	f.Errorf(format, args...)
+}
+
+func TestMustNew(t *testing.T) {
+	fake := &fakeTB{TB: t}
+	idtest.AssertNotZero(fake, idtest.MustNew(fake))
+	if len(fake.errors) != 0 {
+		t.Errorf("unexpected failures: %v", fake.errors)
+	}
+}
+
+func TestAssertEqual(t *testing.T) {
+	a := id.NewId()
+	same, err := id.FromString(a.ToString())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fake := &fakeTB{TB: t}
+	idtest.AssertEqual(fake, same, a)
+	idtest.AssertEqual(fake, nil, nil)
+	if len(fake.errors) != 0 {
+		t.Fatalf("unexpected failures: %v", fake.errors)
+	}
+
+	b := id.NewId()
+	idtest.AssertEqual(fake, a, b)
+	idtest.AssertEqual(fake, nil, b)
+	if len(fake.errors) != 2 {
+		t.Fatalf("expected 2 failures, got %v", fake.errors)
+	}
+	if !strings.Contains(fake.errors[0], a.ToString()) || !strings.Contains(fake.errors[0], b.ToString()) {
+		t.Errorf("failure %q does not show both ids", fake.errors[0])
+	}
+	if !strings.Contains(fake.errors[1], "<nil>") {
+		t.Errorf("failure %q does not show the nil id", fake.errors[1])
+	}
+}
+
+func TestAssertNotZero(t *testing.T) {
+	fake := &fakeTB{TB: t}
+	idtest.AssertNotZero(fake, nil)
+	idtest.AssertNotZero(fake, &id.Id{})
+	if len(fake.errors) != 2 {
+		t.Fatalf("expected 2 failures, got %v", fake.errors)
+	}
+	if !strings.Contains(fake.errors[1], "00000000-0000-0000-0000-000000000000") {
+		t.Errorf("failure %q does not show the zero id", fake.errors[1])
+	}
+}
+
+func TestIDComparer(t *testing.T) {
+	type user struct {
+		ID      *id.Id
+		OrgID   id.Id
+		Parents []*id.Id
+		Name    string
+	}
+
+	i, org := idtest.MustNew(t), idtest.MustNew(t)
+	want := user{ID: i, OrgID: *org, Parents: []*id.Id{org, nil}, Name: "ada"}
+	got := user{ID: id.FromUUID(i.ToUUID()), OrgID: *id.FromUUID(org.ToUUID()), Parents: []*id.Id{id.FromUUID(org.ToUUID()), nil}, Name: "ada"}
+
+	if diff := cmp.Diff(want, got, idtest.IDComparer()); diff != "" {
+		t.Errorf("equal ids differ (-want +got):\n%s", diff)
+	}
+
+	got.ID = idtest.MustNew(t)
+	if cmp.Equal(want, got, idtest.IDComparer()) {
+		t.Error("different ids compare equal")
+	}
+}
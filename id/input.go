@@ -0,0 +1,44 @@
+package id
+
+import "strings"
+
+// userInputPairs are the surrounding characters removed by FromUserInput.
+var userInputPairs = [][2]byte{
+	{'"', '"'}, {'\'', '\''}, {'`', '`'},
+	{'(', ')'}, {'[', ']'}, {'{', '}'}, {'<', '>'},
+}
+
+// FromUserInput converts an Id pasted by a user, e.g. into a search box, to an Id.
+// It is a lenient alternative to the strict FromString: before parsing, it repeatedly
+// strips, in this order,
+//   - leading and trailing whitespace,
+//   - trailing punctuation among . , ; : ! ?
+//   - one matching pair of surrounding double quotes, single quotes, backquotes,
+//     parentheses, square brackets, braces or angle brackets,
+//
+// until none apply. The remaining text is parsed with FromString, so every form it
+// accepts, such as urn:uuid:, is accepted too.
+func FromUserInput(s string) (*Id, error) {
+	for {
+		trimmed := strings.TrimRight(strings.TrimSpace(s), ".,;:!?")
+		trimmed = trimPair(trimmed)
+		if trimmed == s {
+			break
+		}
+		s = trimmed
+	}
+	return FromString(s)
+}
+
+// trimPair removes one pair of surrounding characters of userInputPairs from s.
+func trimPair(s string) string {
+	if len(s) < 2 {
+		return s
+	}
+	for _, pair := range userInputPairs {
+		if s[0] == pair[0] && s[len(s)-1] == pair[1] {
+			return s[1 : len(s)-1]
+		}
+	}
+	return s
+}
@@ -0,0 +1,29 @@
+package id
+
+import "encoding/binary"
+
+// High64 returns the high 64 bits of the Id, i.e. its first 8 bytes in big-endian order.
+// It is safe to call on a nil Id, which returns 0.
+func (id *Id) High64() uint64 {
+	u := id.ToUUID()
+	return binary.BigEndian.Uint64(u[:8])
+}
+
+// Low64 returns the low 64 bits of the Id, i.e. its last 8 bytes in big-endian order.
+// It is safe to call on a nil Id, which returns 0.
+//
+// The low bits alone do not identify an Id: storing only them loses the version and
+// the high half, so different Ids can share the same Low64 and the Id cannot be restored.
+func (id *Id) Low64() uint64 {
+	u := id.ToUUID()
+	return binary.BigEndian.Uint64(u[8:])
+}
+
+// FromUint128 creates an Id from its high and low 64 bits, as returned by High64 and Low64.
+// It does not validate the version or reject the nil UUID.
+func FromUint128(high, low uint64) *Id {
+	var id Id
+	binary.BigEndian.PutUint64(id[:8], high)
+	binary.BigEndian.PutUint64(id[8:], low)
+	return &id
+}
@@ -0,0 +1,20 @@
+package id
+
+import "github.com/google/uuid"
+
+// Key is a value representation of an Id that can be used as a map key.
+// Using *Id as a map key compares pointers rather than UUIDs, so two *Id holding
+// the same UUID are different keys; map[Key]T keys by value instead.
+type Key uuid.UUID
+
+// Key returns the value key of the Id.
+// It is safe to call on a nil Id, which returns the key of the nil UUID.
+func (id *Id) Key() Key {
+	return Key(id.ToUUID())
+}
+
+// Id returns a new Id holding the key's UUID.
+func (k Key) Id() *Id {
+	id := Id(k)
+	return &id
+}
@@ -0,0 +1,22 @@
+package id
+
+import (
+	"errors"
+
+	"github.com/ckminhano/golib/apperror"
+)
+
+// errMismatch is the cause of the AppError returned by MatchOrError.
+var errMismatch = errors.New("id: version mismatch")
+
+// MatchOrError compares an incoming version token against the stored one, as used for
+// optimistic locking. It returns nil when they are equal, otherwise a Conflict AppError
+// holding both ids in the "have" and "want" metadata keys.
+func MatchOrError(have, want *Id) *apperror.AppError {
+	if have.ToUUID() == want.ToUUID() {
+		return nil
+	}
+	return apperror.Conflict(errMismatch).
+		WithMetadata("have", have.ToString()).
+		WithMetadata("want", want.ToString())
+}
@@ -0,0 +1,21 @@
+package id
+
+import "fmt"
+
+// canonicalLen is the length of the bare canonical UUID form xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx.
+const canonicalLen = 36
+
+// PathSegment returns the Id in the bare canonical UUID form, safe to use as a URL path segment.
+func (id *Id) PathSegment() string {
+	return id.ToString()
+}
+
+// FromPathSegment converts a URL path segment to an Id for strict routing.
+// Unlike FromString, it only accepts the bare canonical form and rejects the
+// braced, urn:uuid: and unhyphenated forms.
+func FromPathSegment(s string) (*Id, error) {
+	if len(s) != canonicalLen {
+		return nil, fmt.Errorf("invalid id path segment %q: expected the canonical UUID form", s)
+	}
+	return FromString(s)
+}
@@ -0,0 +1,47 @@
+package id
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/ckminhano/golib/apperror"
+)
+
+var (
+	idType    = reflect.TypeFor[Id]()
+	idPtrType = reflect.TypeFor[*Id]()
+)
+
+// RequireIDs checks that the named Id or *Id fields of the struct v, or of the struct v
+// points to, are set, i.e. not nil and not the nil UUID. It returns nil if they all are,
+// otherwise an ErrValidation multi-error with a "required" field error for each missing field.
+// It panics if v is not a struct or a pointer to one, or if a field does not exist or is
+// not an Id or *Id, since those are programming errors.
+func RequireIDs(v any, fields ...string) *apperror.AppError {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if rv.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("id.RequireIDs: %T is not a struct or a pointer to a struct", v))
+	}
+
+	b := apperror.NewValidationBuilder()
+	for _, name := range fields {
+		f := rv.FieldByName(name)
+		if !f.IsValid() {
+			panic(fmt.Sprintf("id.RequireIDs: %s has no field %s", rv.Type(), name))
+		}
+
+		var set bool
+		switch f.Type() {
+		case idType:
+			set = !f.IsZero()
+		case idPtrType:
+			set = !f.IsNil() && !f.Elem().IsZero()
+		default:
+			panic(fmt.Sprintf("id.RequireIDs: field %s.%s is a %s, not an Id or *Id", rv.Type(), name, f.Type()))
+		}
+		if !set {
+			b.AddFieldWithCode(name, "required", name+" is required")
+		}
+	}
+	return b.Err()
+}
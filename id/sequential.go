@@ -0,0 +1,28 @@
+package id
+
+import (
+	"encoding/binary"
+	"sync/atomic"
+)
+
+// SequentialGenerator produces deterministic, incrementing Ids such as
+// 00000000-0000-0000-0000-000000000001, 00000000-0000-0000-0000-000000000002, ...
+// It is meant for golden tests only and is never used by NewId.
+// It is safe for concurrent use.
+type SequentialGenerator struct {
+	counter atomic.Uint64
+}
+
+// NewSequentialGenerator creates a SequentialGenerator whose first Id is seed + 1.
+func NewSequentialGenerator(seed uint64) *SequentialGenerator {
+	g := &SequentialGenerator{}
+	g.counter.Store(seed)
+	return g
+}
+
+// Next returns the next Id in the sequence.
+func (g *SequentialGenerator) Next() *Id {
+	var id Id
+	binary.BigEndian.PutUint64(id[8:], g.counter.Add(1))
+	return &id
+}
@@ -0,0 +1,49 @@
+package id
+
+// IdSet is a set of Ids keyed by value.
+type IdSet map[Key]struct{}
+
+// NewIdSet returns a set holding the given Ids.
+func NewIdSet(ids ...*Id) IdSet {
+	set := make(IdSet, len(ids))
+	for _, id := range ids {
+		set.Add(id)
+	}
+	return set
+}
+
+// Add adds the Id to the set.
+func (s IdSet) Add(id *Id) {
+	s[id.Key()] = struct{}{}
+}
+
+// Contains reports whether the Id is in the set.
+func (s IdSet) Contains(id *Id) bool {
+	_, ok := s[id.Key()]
+	return ok
+}
+
+// Missing returns the candidates that are not in present, in their original order.
+func Missing(candidates []*Id, present IdSet) []*Id {
+	var missing []*Id
+	for _, id := range candidates {
+		if !present.Contains(id) {
+			missing = append(missing, id)
+		}
+	}
+	return missing
+}
+
+// Intersect returns a new set holding the Ids that are in both a and b.
+func Intersect(a, b IdSet) IdSet {
+	if len(b) < len(a) {
+		a, b = b, a
+	}
+	set := make(IdSet)
+	for k := range a {
+		if _, ok := b[k]; ok {
+			set[k] = struct{}{}
+		}
+	}
+	return set
+}
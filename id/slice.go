@@ -0,0 +1,65 @@
+package id
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ToStrings converts a slice of Ids to their string representations.
+// Nil entries are skipped, so the output keeps the order of the input and always
+// round-trips through FromStrings.
+func ToStrings(ids []*Id) []string {
+	ss := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if id == nil {
+			continue
+		}
+		ss = append(ss, id.ToString())
+	}
+	return ss
+}
+
+// FromStrings converts a slice of strings to Ids using FromString.
+// It stops at the first invalid entry, including an empty string, and returns an error
+// reporting its index and value.
+func FromStrings(ss []string) ([]*Id, error) {
+	ids := make([]*Id, len(ss))
+	for i, s := range ss {
+		id, err := FromString(s)
+		if err != nil {
+			return nil, fmt.Errorf("index %d: %w", i, err)
+		}
+		ids[i] = id
+	}
+	return ids, nil
+}
+
+// ScanIds reads one Id per line from r using FromString, skipping blank lines.
+// It returns the Ids that were parsed and an error for each invalid line reporting its
+// line number. An error reading r is returned as the last error.
+func ScanIds(r io.Reader) ([]*Id, []error) {
+	var (
+		ids  []*Id
+		errs []error
+	)
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		s := strings.TrimSpace(scanner.Text())
+		if s == "" {
+			continue
+		}
+		id, err := FromString(s)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("line %d: %w", line, err))
+			continue
+		}
+		ids = append(ids, id)
+	}
+	if err := scanner.Err(); err != nil {
+		errs = append(errs, err)
+	}
+	return ids, errs
+}
@@ -0,0 +1,32 @@
+package id
+
+import (
+	"bytes"
+	"sort"
+)
+
+// Ids is a slice of Ids that implements sort.Interface by comparing the raw bytes
+// of each Id. Nil entries sort first.
+type Ids []*Id
+
+func (ids Ids) Len() int {
+	return len(ids)
+}
+
+func (ids Ids) Less(i, j int) bool {
+	a, b := ids[i], ids[j]
+	if a == nil || b == nil {
+		return a == nil && b != nil
+	}
+	return bytes.Compare(a[:], b[:]) < 0
+}
+
+func (ids Ids) Swap(i, j int) {
+	ids[i], ids[j] = ids[j], ids[i]
+}
+
+// Sort sorts the Ids in place in ascending byte order, so equal sets always end up
+// in the same order.
+func (ids Ids) Sort() {
+	sort.Stable(ids)
+}
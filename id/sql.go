@@ -0,0 +1,69 @@
+package id
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Value implements the driver.Valuer interface, storing the Id as its string form.
+// A nil Id is stored as NULL.
+func (id *Id) Value() (driver.Value, error) {
+	if id == nil {
+		return nil, nil
+	}
+	return id.ToString(), nil
+}
+
+// Scan implements the sql.Scanner interface.
+// It accepts the string form of a UUID, as a string or []byte, or its raw 16 bytes.
+// It returns ErrNilUUID for the nil UUID and an error for NULL.
+func (id *Id) Scan(src any) error {
+	var (
+		parsed *Id
+		err    error
+	)
+	switch v := src.(type) {
+	case string:
+		parsed, err = FromString(v)
+	case []byte:
+		if len(v) == 16 {
+			parsed, err = FromBytes(v)
+		} else {
+			parsed, err = FromString(string(v))
+		}
+	case nil:
+		return errors.New("id: cannot scan NULL into Id")
+	default:
+		return fmt.Errorf("id: cannot scan %T into Id", src)
+	}
+	if err != nil {
+		return err
+	}
+	*id = *parsed
+	return nil
+}
+
+// Placeholders returns a VALUES list of numbered placeholders, one row per Id, e.g.
+// "($1),($2),($3)" for three ids and startAt 1, and the matching query arguments holding
+// each Id as its driver.Valuer value. It returns "" and nil for an empty slice.
+func Placeholders(ids []*Id, startAt int) (string, []any) {
+	if len(ids) == 0 {
+		return "", nil
+	}
+
+	var b strings.Builder
+	args := make([]any, len(ids))
+	for i, id := range ids {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString("($")
+		b.WriteString(strconv.Itoa(startAt + i))
+		b.WriteByte(')')
+		args[i], _ = id.Value() // Value never fails.
+	}
+	return b.String(), args
+}
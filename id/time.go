@@ -0,0 +1,28 @@
+package id
+
+import (
+	"encoding/binary"
+	"time"
+)
+
+// Time returns the creation time embedded in a v7 Id, with millisecond precision.
+// It returns false if the Id is nil or not a v7 UUID.
+func (id *Id) Time() (time.Time, bool) {
+	if id == nil || id.Version() != 7 {
+		return time.Time{}, false
+	}
+
+	var ms [8]byte
+	copy(ms[2:], id[:6])
+	return time.UnixMilli(int64(binary.BigEndian.Uint64(ms[:]))), true
+}
+
+// CreatedBetween reports whether a v7 Id was created in the window [start, end).
+// It returns false for ids that are not v7.
+func (id *Id) CreatedBetween(start, end time.Time) bool {
+	t, ok := id.Time()
+	if !ok {
+		return false
+	}
+	return !t.Before(start) && t.Before(end)
+}
@@ -0,0 +1,49 @@
+package id
+
+import (
+	"errors"
+	"sync"
+)
+
+// ErrCollision is returned by UniqueGenerator when it cannot produce an Id that was not issued before.
+var ErrCollision = errors.New("id: could not generate a unique id")
+
+// UniqueGenerator wraps a Source, such as a SequentialGenerator, and guarantees
+// that it never returns the same Id twice by tracking issued Ids and regenerating on collision.
+// The set of issued Ids grows with every call, so it is meant for bounded runs such as tests.
+// It is safe for concurrent use.
+type UniqueGenerator struct {
+	mu         sync.Mutex
+	source     Source
+	maxRetries int
+	issued     IdSet
+}
+
+// NewUniqueGenerator creates a UniqueGenerator drawing Ids from source, or random Ids from
+// NewId if source is nil, and retrying at most maxRetries times when it returns an Id that
+// was already issued. Use SourceFunc to draw Ids from a function.
+func NewUniqueGenerator(source Source, maxRetries int) *UniqueGenerator {
+	if source == nil {
+		source = randomSource{}
+	}
+	return &UniqueGenerator{
+		source:     source,
+		maxRetries: maxRetries,
+		issued:     make(IdSet),
+	}
+}
+
+// Next returns an Id that was not returned before, or ErrCollision when every attempt collided.
+func (g *UniqueGenerator) Next() (*Id, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	for attempt := 0; attempt <= g.maxRetries; attempt++ {
+		id := g.source.Next()
+		if !g.issued.Contains(id) {
+			g.issued.Add(id)
+			return id, nil
+		}
+	}
+	return nil, ErrCollision
+}